@@ -106,13 +106,16 @@ func TestE2EFlow(t *testing.T) {
 	logger := testLogger()
 
 	// Create Slurm client
-	slurmClient := slurm.NewClient(
+	slurmClient, err := slurm.NewClient(
 		cfg.SlurmAPIEndpoint,
 		cfg.SlurmAPIVersion,
 		cfg.SlurmAPIUsername,
 		cfg.SlurmAPIToken,
 		logger,
 	)
+	if err != nil {
+		t.Fatalf("Failed to create Slurm client: %v", err)
+	}
 
 	// Create discovery service
 	discoveryService, err := discovery.NewService(slurmClient, cfg, logger)
@@ -4,25 +4,199 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"text/template"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the program configuration
 type Config struct {
-	SlurmAPIEndpoint string      `yaml:"slurm_api_endpoint"`
-	SlurmAPIVersion  string      `yaml:"slurm_api_version"`
-	SlurmAPIToken    string      `yaml:"slurm_api_token,omitempty"`
-	SlurmAPIUsername string      `yaml:"slurm_api_username,omitempty"`
-	ListenAddress    string      `yaml:"listen_address"`
-	UpdateInterval   string      `yaml:"update_interval"`
-	Jobs             []JobConfig `yaml:"jobs"`
+	SlurmAPIEndpoint        string      `yaml:"slurm_api_endpoint"`
+	SlurmAPIVersion         string      `yaml:"slurm_api_version"`
+	SlurmAPIToken           string      `yaml:"slurm_api_token,omitempty"`
+	SlurmAPITokenFile       string      `yaml:"slurm_api_token_file,omitempty"`
+	SlurmAPIUsername        string      `yaml:"slurm_api_username,omitempty"`
+	SlurmJWTKeyFile         string      `yaml:"slurm_jwt_key_file,omitempty"`
+	SlurmJWTLifespan        string      `yaml:"slurm_jwt_lifespan,omitempty"`
+	SlurmTLSConfig          *TLSConfig  `yaml:"slurm_tls_config,omitempty"`
+	SlurmAPIRetry           RetryConfig `yaml:"slurm_api_retry,omitempty"`
+	ListenAddress           string      `yaml:"listen_address"`
+	UpdateInterval          string      `yaml:"update_interval"`
+	RetryInterval           string      `yaml:"retry_interval,omitempty"`
+	IncrementalUpdates      bool        `yaml:"incremental_updates,omitempty"`
+	FullResyncInterval      string      `yaml:"full_resync_interval,omitempty"`
+	MaxTargetDropPercent    float64     `yaml:"max_target_drop_percent,omitempty"`
+	TargetDropConfirmations int         `yaml:"target_drop_confirmations,omitempty"`
+	DefaultIncludeStates    []string    `yaml:"default_include_states,omitempty"`
+	DefaultExcludeStates    []string    `yaml:"default_exclude_states,omitempty"`
+	AddressFamily           string      `yaml:"address_family,omitempty"`
+	Jobs                    []JobConfig `yaml:"jobs"`
 }
 
+// TLSConfig represents the TLS configuration for connecting to the Slurm REST API
+type TLSConfig struct {
+	CAFile             string `yaml:"ca_file,omitempty"`
+	CertFile           string `yaml:"cert_file,omitempty"`
+	KeyFile            string `yaml:"key_file,omitempty"`
+	ServerName         string `yaml:"server_name,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"`
+}
+
+// RetryConfig represents the retry policy for Slurm REST API requests
+type RetryConfig struct {
+	MaxAttempts int    `yaml:"max_attempts,omitempty"`
+	BaseBackoff string `yaml:"base_backoff,omitempty"`
+	MaxBackoff  string `yaml:"max_backoff,omitempty"`
+}
+
+// Address family preferences for nodes that report several addresses
+const (
+	// AddressFamilyAny uses the first address reported by Slurm
+	AddressFamilyAny = "any"
+	// AddressFamilyIPv4 prefers IPv4 addresses
+	AddressFamilyIPv4 = "ipv4"
+	// AddressFamilyIPv6 prefers IPv6 addresses
+	AddressFamilyIPv6 = "ipv6"
+)
+
+// Target grouping modes of a job
+const (
+	// GroupByNode emits one target group per node and partition
+	GroupByNode = "per_node"
+	// GroupByPartition emits one target group per partition with only the partition and job labels
+	GroupByPartition = "per_partition"
+	// GroupByLabelSet merges targets whose labels are identical once the node name is dropped
+	GroupByLabelSet = "per_label_set"
+)
+
+// Kinds of targets a job discovers
+const (
+	// JobKindNode emits a target for every selected Slurm node
+	JobKindNode = "node"
+	// JobKindSlurmJob emits a target for every node of every running Slurm job
+	JobKindSlurmJob = "slurm_job"
+)
+
 // JobConfig represents the configuration for a Prometheus target job
 type JobConfig struct {
 	Name string `yaml:"name"`
+	// Kind is JobKindNode (the default) or JobKindSlurmJob
+	Kind string `yaml:"kind,omitempty"`
 	Port int    `yaml:"port"`
+	// Ports lists several exporter ports; when set, Port is ignored
+	Ports []PortConfig `yaml:"ports,omitempty"`
+	// PortOverrides replace the ports of nodes matched by a selector.
+	// The first matching override wins.
+	PortOverrides []PortOverride `yaml:"port_overrides,omitempty"`
+	// AddressTemplate is a text/template rendering the host of each target
+	AddressTemplate string `yaml:"address_template,omitempty"`
+	GroupBy         string `yaml:"group_by,omitempty"`
+	// DedupeNodes emits a node once per job instead of once per partition
+	DedupeNodes bool `yaml:"dedupe_nodes,omitempty"`
+	// IncludeStates and ExcludeStates filter nodes by base state or state flag.
+	// When unset, the global defaults apply; an empty list disables the default.
+	IncludeStates []string `yaml:"include_states,omitempty"`
+	ExcludeStates []string `yaml:"exclude_states,omitempty"`
+	// IncludePartitionless emits nodes that belong to no partition, using
+	// PartitionlessPlaceholder as their partition
+	IncludePartitionless     bool   `yaml:"include_partitionless,omitempty"`
+	PartitionlessPlaceholder string `yaml:"partitionless_placeholder,omitempty"`
+	// AnnotateJobs adds the running Slurm jobs of each node to its labels
+	AnnotateJobs bool `yaml:"annotate_jobs,omitempty"`
+	// AnnotatePartitions adds the attributes of the target's partition to its labels
+	AnnotatePartitions bool `yaml:"annotate_partitions,omitempty"`
+	// OnlyUpPartitions drops targets in partitions whose state is not UP
+	OnlyUpPartitions bool `yaml:"only_up_partitions,omitempty"`
+	// Metadata reads ports and labels from key=value pairs in the free-form
+	// fields of Slurm jobs; slurm_job jobs only
+	Metadata *JobMetadata `yaml:"metadata,omitempty"`
+	// NodeSelector restricts the nodes and partitions the job applies to
+	NodeSelector `yaml:",inline"`
+}
+
+// ParseAddressTemplate parses the address template of a job.
+// It returns nil when the job has no address template.
+func (job JobConfig) ParseAddressTemplate() (*template.Template, error) {
+	if job.AddressTemplate == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(job.Name).Option("missingkey=zero").Parse(job.AddressTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Free-form Slurm job fields that job metadata can be read from
+const (
+	JobFieldComment      = "comment"
+	JobFieldAdminComment = "admin_comment"
+	JobFieldExtra        = "extra"
+)
+
+// JobMetadata configures how key=value pairs such as "prom_port=8000" are
+// parsed from the free-form fields of Slurm jobs
+type JobMetadata struct {
+	// Fields lists the job fields to parse, in order; when a label key is
+	// set several times the last value wins. Defaults to the comment only.
+	Fields []string `yaml:"fields,omitempty"`
+	// PortKey is the key holding the exporter port, "prom_port" by default.
+	// The key may be repeated to scrape several ports.
+	PortKey string `yaml:"port_key,omitempty"`
+	// Separator separates the pairs; whitespace, commas and semicolons by default
+	Separator string `yaml:"separator,omitempty"`
+	// LabelKeys restricts the keys exposed as labels; empty means all keys
+	// other than the port key
+	LabelKeys []string `yaml:"label_keys,omitempty"`
+}
+
+// PortConfig represents a named exporter port
+type PortConfig struct {
+	Name string `yaml:"name,omitempty"`
+	Port int    `yaml:"port"`
+}
+
+// PortOverride represents the ports used for the nodes matched by a selector
+type PortOverride struct {
+	NodeSelector `yaml:",inline"`
+	Ports        []PortConfig `yaml:"ports"`
+}
+
+// TargetPorts returns the ports of a job, falling back to its single port
+func (job JobConfig) TargetPorts() []PortConfig {
+	if len(job.Ports) > 0 {
+		return job.Ports
+	}
+	return []PortConfig{{Port: job.Port}}
+}
+
+// NodeSelector selects nodes by partition, feature, GRES and name.
+// All conditions that are set must match.
+type NodeSelector struct {
+	// Partitions the node is emitted under; empty means all
+	Partitions        []string `yaml:"partitions,omitempty"`
+	ExcludePartitions []string `yaml:"exclude_partitions,omitempty"`
+	// Features that must all be available or active on the node
+	Features []string `yaml:"features,omitempty"`
+	// Gres is an anchored regular expression matched against the node's GRES
+	Gres string `yaml:"gres,omitempty"`
+	// NodeNameRegex is an anchored regular expression matched against the node name
+	NodeNameRegex string `yaml:"node_name_regex,omitempty"`
+	// Nodes is a Slurm hostlist expression such as "gpu[001-064],login01"
+	Nodes string `yaml:"nodes,omitempty"`
+}
+
+// StateFilter returns the include and exclude state lists of a job,
+// falling back to the global defaults for lists the job does not set
+func (cfg *Config) StateFilter(job JobConfig) (include, exclude []string) {
+	include, exclude = job.IncludeStates, job.ExcludeStates
+	if include == nil {
+		include = cfg.DefaultIncludeStates
+	}
+	if exclude == nil {
+		exclude = cfg.DefaultExcludeStates
+	}
+	return include, exclude
 }
 
 // LoadConfig loads configuration from a YAML file
@@ -40,19 +214,42 @@ func LoadConfig(path string) (*Config, error) {
 func LoadConfigFromReader(r io.Reader) (*Config, error) {
 	var cfg Config
 	decoder := yaml.NewDecoder(r)
-	if err := decoder.Decode(&cfg); err != nil {
-		// Check for EOF (empty file) and return default config
-		if err == io.EOF {
-			// Set default values for empty config
-			cfg.ListenAddress = ":8080"
-			cfg.SlurmAPIVersion = "v0.0.38"
-			cfg.UpdateInterval = "5m"
-			return &cfg, nil
-		}
+	// An empty file (EOF) yields the default config
+	if err := decoder.Decode(&cfg); err != nil && err != io.EOF {
 		return nil, fmt.Errorf("failed to decode config: %w", err)
 	}
 
-	// Set default values
+	cfg.applyDefaults()
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// validate checks options that can be verified without contacting Slurm
+func (cfg *Config) validate() error {
+	switch cfg.AddressFamily {
+	case AddressFamilyAny, AddressFamilyIPv4, AddressFamilyIPv6:
+	default:
+		return fmt.Errorf("invalid address_family %q", cfg.AddressFamily)
+	}
+	if cfg.MaxTargetDropPercent < 0 || cfg.MaxTargetDropPercent > 100 {
+		return fmt.Errorf("max_target_drop_percent must be between 0 and 100, got %v", cfg.MaxTargetDropPercent)
+	}
+	// A single confirmation would accept the first excessive drop
+	if cfg.MaxTargetDropPercent > 0 && cfg.TargetDropConfirmations < 2 {
+		return fmt.Errorf("target_drop_confirmations must be at least 2, got %d", cfg.TargetDropConfirmations)
+	}
+	for _, job := range cfg.Jobs {
+		if _, err := job.ParseAddressTemplate(); err != nil {
+			return fmt.Errorf("job %q: %w", job.Name, err)
+		}
+	}
+	return nil
+}
+
+// applyDefaults sets default values for unset options
+func (cfg *Config) applyDefaults() {
 	if cfg.ListenAddress == "" {
 		cfg.ListenAddress = ":8080"
 	}
@@ -62,6 +259,25 @@ func LoadConfigFromReader(r io.Reader) (*Config, error) {
 	if cfg.UpdateInterval == "" {
 		cfg.UpdateInterval = "5m"
 	}
-
-	return &cfg, nil
+	if cfg.FullResyncInterval == "" {
+		cfg.FullResyncInterval = "1h"
+	}
+	if cfg.AddressFamily == "" {
+		cfg.AddressFamily = AddressFamilyAny
+	}
+	if cfg.TargetDropConfirmations == 0 {
+		cfg.TargetDropConfirmations = 3
+	}
+	if cfg.RetryInterval == "" {
+		cfg.RetryInterval = "30s"
+	}
+	if cfg.SlurmAPIRetry.MaxAttempts == 0 {
+		cfg.SlurmAPIRetry.MaxAttempts = 3
+	}
+	if cfg.SlurmAPIRetry.BaseBackoff == "" {
+		cfg.SlurmAPIRetry.BaseBackoff = "1s"
+	}
+	if cfg.SlurmAPIRetry.MaxBackoff == "" {
+		cfg.SlurmAPIRetry.MaxBackoff = "30s"
+	}
 }
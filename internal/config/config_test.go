@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -77,6 +78,189 @@ jobs:
 				return true
 			},
 		},
+		{
+			name: "tls config",
+			input: `
+slurm_api_endpoint: "https://slurm-api:6820"
+slurm_tls_config:
+  ca_file: /etc/prometheus-slurm-sd/ca.pem
+  cert_file: /etc/prometheus-slurm-sd/client.pem
+  key_file: /etc/prometheus-slurm-sd/client-key.pem
+  server_name: slurmrestd.example.com
+  insecure_skip_verify: true
+`,
+			wantErr: false,
+			validateCfg: func(cfg *Config) bool {
+				tlsCfg := cfg.SlurmTLSConfig
+				if tlsCfg == nil {
+					return false
+				}
+				return tlsCfg.CAFile == "/etc/prometheus-slurm-sd/ca.pem" &&
+					tlsCfg.CertFile == "/etc/prometheus-slurm-sd/client.pem" &&
+					tlsCfg.KeyFile == "/etc/prometheus-slurm-sd/client-key.pem" &&
+					tlsCfg.ServerName == "slurmrestd.example.com" &&
+					tlsCfg.InsecureSkipVerify
+			},
+		},
+		{
+			name: "job options",
+			input: `
+slurm_api_endpoint: "http://slurm-api:6820"
+jobs:
+  - name: node
+    kind: node
+    port: 9100
+    group_by: per_partition
+    dedupe_nodes: true
+    include_states: [IDLE, MIXED]
+    exclude_states: []
+    partitions: [gpu]
+    features: [ib]
+    nodes: "gpu[001-064]"
+    ports:
+      - name: main
+        port: 9100
+    address_template: "{{ .Node.Name }}.ib0"
+    include_partitionless: true
+    partitionless_placeholder: none
+    annotate_jobs: true
+    annotate_partitions: true
+    only_up_partitions: true
+    port_overrides:
+      - partitions: [legacy]
+        ports:
+          - name: main
+            port: 19100
+`,
+			wantErr: false,
+			validateCfg: func(cfg *Config) bool {
+				return len(cfg.Jobs) == 1 &&
+					cfg.Jobs[0].Kind == JobKindNode &&
+					cfg.Jobs[0].GroupBy == GroupByPartition &&
+					cfg.Jobs[0].DedupeNodes &&
+					len(cfg.Jobs[0].IncludeStates) == 2 &&
+					cfg.Jobs[0].ExcludeStates != nil &&
+					reflect.DeepEqual(cfg.Jobs[0].Partitions, []string{"gpu"}) &&
+					reflect.DeepEqual(cfg.Jobs[0].Features, []string{"ib"}) &&
+					cfg.Jobs[0].Nodes == "gpu[001-064]" &&
+					cfg.Jobs[0].AddressTemplate == "{{ .Node.Name }}.ib0" &&
+					cfg.Jobs[0].IncludePartitionless &&
+					cfg.Jobs[0].PartitionlessPlaceholder == "none" &&
+					cfg.Jobs[0].AnnotateJobs &&
+					cfg.Jobs[0].AnnotatePartitions &&
+					cfg.Jobs[0].OnlyUpPartitions &&
+					reflect.DeepEqual(cfg.Jobs[0].TargetPorts(), []PortConfig{{Name: "main", Port: 9100}}) &&
+					len(cfg.Jobs[0].PortOverrides) == 1 &&
+					reflect.DeepEqual(cfg.Jobs[0].PortOverrides[0].Partitions, []string{"legacy"}) &&
+					cfg.Jobs[0].PortOverrides[0].Ports[0].Port == 19100
+			},
+		},
+		{
+			name: "slurm job metadata",
+			input: `
+slurm_api_endpoint: "http://slurm-api:6820"
+jobs:
+  - name: user
+    kind: slurm_job
+    metadata:
+      fields: [comment, extra]
+      port_key: metrics_port
+      separator: ";"
+      label_keys: [team]
+`,
+			wantErr: false,
+			validateCfg: func(cfg *Config) bool {
+				m := cfg.Jobs[0].Metadata
+				return m != nil &&
+					reflect.DeepEqual(m.Fields, []string{JobFieldComment, JobFieldExtra}) &&
+					m.PortKey == "metrics_port" &&
+					m.Separator == ";" &&
+					reflect.DeepEqual(m.LabelKeys, []string{"team"})
+			},
+		},
+		{
+			name: "invalid address template",
+			input: `
+slurm_api_endpoint: "http://slurm-api:6820"
+jobs:
+  - name: node
+    port: 9100
+    address_template: "{{ .Node.Name "
+`,
+			wantErr: true,
+			validateCfg: func(cfg *Config) bool {
+				return true // Not used in error case
+			},
+		},
+		{
+			name: "invalid address family",
+			input: `
+slurm_api_endpoint: "http://slurm-api:6820"
+address_family: ipv5
+`,
+			wantErr: true,
+			validateCfg: func(cfg *Config) bool {
+				return true // Not used in error case
+			},
+		},
+		{
+			name: "target drop percent above 100",
+			input: `
+slurm_api_endpoint: "http://slurm-api:6820"
+max_target_drop_percent: 150
+`,
+			wantErr: true,
+			validateCfg: func(cfg *Config) bool {
+				return true // Not used in error case
+			},
+		},
+		{
+			name: "negative target drop percent",
+			input: `
+slurm_api_endpoint: "http://slurm-api:6820"
+max_target_drop_percent: -10
+`,
+			wantErr: true,
+			validateCfg: func(cfg *Config) bool {
+				return true // Not used in error case
+			},
+		},
+		{
+			name: "single target drop confirmation",
+			input: `
+slurm_api_endpoint: "http://slurm-api:6820"
+max_target_drop_percent: 50
+target_drop_confirmations: 1
+`,
+			wantErr: true,
+			validateCfg: func(cfg *Config) bool {
+				return true // Not used in error case
+			},
+		},
+		{
+			name: "negative target drop confirmations",
+			input: `
+slurm_api_endpoint: "http://slurm-api:6820"
+max_target_drop_percent: 50
+target_drop_confirmations: -2
+`,
+			wantErr: true,
+			validateCfg: func(cfg *Config) bool {
+				return true // Not used in error case
+			},
+		},
+		{
+			name: "target drop guard",
+			input: `
+slurm_api_endpoint: "http://slurm-api:6820"
+max_target_drop_percent: 100
+target_drop_confirmations: 2
+`,
+			wantErr: false,
+			validateCfg: func(cfg *Config) bool {
+				return cfg.MaxTargetDropPercent == 100 && cfg.TargetDropConfirmations == 2
+			},
+		},
 		{
 			name: "invalid yaml",
 			input: `
@@ -98,7 +282,12 @@ jobs:
 			validateCfg: func(cfg *Config) bool {
 				return cfg.SlurmAPIVersion == "v0.0.38" &&
 					cfg.ListenAddress == ":8080" &&
-					cfg.UpdateInterval == "5m"
+					cfg.UpdateInterval == "5m" &&
+					cfg.RetryInterval == "30s" &&
+					cfg.SlurmAPIRetry.MaxAttempts == 3 &&
+					cfg.SlurmAPIRetry.BaseBackoff == "1s" &&
+					cfg.SlurmAPIRetry.MaxBackoff == "30s" &&
+					cfg.AddressFamily == AddressFamilyAny
 			},
 		},
 	}
@@ -120,6 +309,23 @@ jobs:
 	}
 }
 
+func TestConfig_StateFilter(t *testing.T) {
+	cfg := &Config{
+		DefaultIncludeStates: []string{"IDLE"},
+		DefaultExcludeStates: []string{"DOWN"},
+	}
+
+	include, exclude := cfg.StateFilter(JobConfig{Name: "node"})
+	if !reflect.DeepEqual(include, []string{"IDLE"}) || !reflect.DeepEqual(exclude, []string{"DOWN"}) {
+		t.Errorf("Job without lists: got include=%v exclude=%v, want the defaults", include, exclude)
+	}
+
+	include, exclude = cfg.StateFilter(JobConfig{Name: "node", IncludeStates: []string{"MIXED"}, ExcludeStates: []string{}})
+	if !reflect.DeepEqual(include, []string{"MIXED"}) || len(exclude) != 0 {
+		t.Errorf("Job with lists: got include=%v exclude=%v, want [MIXED] and []", include, exclude)
+	}
+}
+
 func TestLoadConfig(t *testing.T) {
 	// Create a temporary config file
 	content := `
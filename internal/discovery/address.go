@@ -0,0 +1,44 @@
+package discovery
+
+import (
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/config"
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+// nodeAddress returns the address at which a node is scraped. When the node
+// reports several addresses, the first one of the preferred family is used,
+// falling back to the first address. Nodes without an address use their hostname.
+func nodeAddress(node slurm.Node, family string) string {
+	addresses := strings.FieldsFunc(node.Address, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(addresses) == 0 {
+		return node.Hostname
+	}
+
+	for _, address := range addresses {
+		ip, err := netip.ParseAddr(address)
+		if err != nil {
+			continue
+		}
+		switch {
+		case family == config.AddressFamilyIPv4 && ip.Unmap().Is4():
+			return address
+		case family == config.AddressFamilyIPv6 && ip.Is6() && !ip.Is4In6():
+			return address
+		}
+	}
+	return addresses[0]
+}
+
+// joinHostPort builds a target address, bracketing IPv6 hosts
+func joinHostPort(host string, port int) string {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
@@ -0,0 +1,106 @@
+package discovery
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/config"
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+func TestNodeAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		family  string
+		want    string
+	}{
+		{name: "single IPv4", address: "10.0.0.1", family: config.AddressFamilyAny, want: "10.0.0.1"},
+		{name: "single IPv6", address: "fd00::1", family: config.AddressFamilyIPv4, want: "fd00::1"},
+		{name: "hostname fallback", address: "", family: config.AddressFamilyAny, want: "node1"},
+		{name: "first address by default", address: "fd00::1,10.0.0.1", family: config.AddressFamilyAny, want: "fd00::1"},
+		{name: "unset family uses the first address", address: "fd00::1,10.0.0.1", family: "", want: "fd00::1"},
+		{name: "prefer IPv4", address: "fd00::1, 10.0.0.1", family: config.AddressFamilyIPv4, want: "10.0.0.1"},
+		{name: "prefer IPv6", address: "10.0.0.1,fd00::1", family: config.AddressFamilyIPv6, want: "fd00::1"},
+		{name: "IPv4-mapped IPv6 is IPv4", address: "::ffff:10.0.0.1,fd00::1", family: config.AddressFamilyIPv6, want: "fd00::1"},
+		{name: "hostnames are kept", address: "node1-ib,10.0.0.1", family: config.AddressFamilyAny, want: "node1-ib"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			node := slurm.Node{Name: "node1", Hostname: "node1", Address: tc.address}
+			if got := nodeAddress(node, tc.family); got != tc.want {
+				t.Errorf("nodeAddress(%q, %q) = %q, want %q", tc.address, tc.family, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJoinHostPort(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "10.0.0.1", want: "10.0.0.1:9100"},
+		{host: "node1.example", want: "node1.example:9100"},
+		{host: "fd00::1", want: "[fd00::1]:9100"},
+		{host: "[fd00::1]", want: "[fd00::1]:9100"},
+		{host: "fe80::1%eth0", want: "[fe80::1%eth0]:9100"},
+	}
+
+	for _, tc := range tests {
+		if got := joinHostPort(tc.host, 9100); got != tc.want {
+			t.Errorf("joinHostPort(%q) = %q, want %q", tc.host, got, tc.want)
+		}
+	}
+}
+
+func TestService_updateTargets_IPv6(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	tests := []struct {
+		family string
+		want   string
+	}{
+		{family: config.AddressFamilyIPv6, want: "[fd00::1]:9100"},
+		{family: config.AddressFamilyIPv4, want: "10.0.0.1:9100"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.family, func(t *testing.T) {
+			cfg := &config.Config{
+				UpdateInterval: "5m",
+				AddressFamily:  tc.family,
+				Jobs: []config.JobConfig{
+					{Name: "node", Port: 9100},
+				},
+			}
+			mockClient := &MockSlurmClient{
+				GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+					return &slurm.NodeInfoResponse{
+						Nodes: []slurm.Node{
+							{Name: "node1", Address: "fd00::1,10.0.0.1", State: []string{"IDLE"}, Partitions: []string{"compute"}},
+						},
+					}, nil
+				},
+			}
+
+			service, err := NewService(mockClient, cfg, logger)
+			if err != nil {
+				t.Fatalf("Failed to create service: %v", err)
+			}
+			if err := service.updateTargets(context.Background()); err != nil {
+				t.Fatalf("updateTargets() error = %v", err)
+			}
+
+			targets, _ := service.GetTargets("node")
+			if len(targets) != 1 || targets[0].Targets[0] != tc.want {
+				t.Errorf("Got targets %+v, want %s", targets, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package discovery
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/config"
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+// matchesStates reports whether a node passes the state filter of a job.
+// States are compared case-insensitively against the base state and all
+// state flags. A node passes when any of its states is included (or the
+// include list is empty) and none of its states is excluded.
+func matchesStates(node slurm.Node, include, exclude []string) bool {
+	hasState := func(states []string) bool {
+		return slices.ContainsFunc(node.State, func(state string) bool {
+			return slices.ContainsFunc(states, func(s string) bool {
+				return strings.EqualFold(s, state)
+			})
+		})
+	}
+
+	if len(include) > 0 && !hasState(include) {
+		return false
+	}
+	return !hasState(exclude)
+}
+
+// nodeSelector is the compiled form of a config.NodeSelector
+type nodeSelector struct {
+	partitions        []string
+	excludePartitions []string
+	features          []string
+	gres              *regexp.Regexp
+	nodeNames         *regexp.Regexp
+	nodes             map[string]struct{}
+}
+
+// newNodeSelector compiles the regular expressions and expands the hostlist of a selector
+func newNodeSelector(cfg config.NodeSelector) (*nodeSelector, error) {
+	sel := &nodeSelector{
+		partitions:        cfg.Partitions,
+		excludePartitions: cfg.ExcludePartitions,
+		features:          cfg.Features,
+	}
+
+	var err error
+	if cfg.Gres != "" {
+		if sel.gres, err = compileAnchored(cfg.Gres); err != nil {
+			return nil, fmt.Errorf("invalid gres regex: %w", err)
+		}
+	}
+	if cfg.NodeNameRegex != "" {
+		if sel.nodeNames, err = compileAnchored(cfg.NodeNameRegex); err != nil {
+			return nil, fmt.Errorf("invalid node name regex: %w", err)
+		}
+	}
+	if cfg.Nodes != "" {
+		hosts, err := slurm.ExpandHostlist(cfg.Nodes)
+		if err != nil {
+			return nil, err
+		}
+		sel.nodes = make(map[string]struct{}, len(hosts))
+		for _, host := range hosts {
+			sel.nodes[host] = struct{}{}
+		}
+	}
+	return sel, nil
+}
+
+// compileAnchored compiles a regular expression that must match the whole value,
+// like the regular expressions in Prometheus relabel configs
+func compileAnchored(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+// matches reports whether the node-level conditions of the selector match.
+// Partitions are checked separately by selectPartitions.
+func (sel *nodeSelector) matches(node slurm.Node) bool {
+	for _, feature := range sel.features {
+		if !slices.Contains(node.Features, feature) && !slices.Contains(node.ActiveFeatures, feature) {
+			return false
+		}
+	}
+	if sel.gres != nil && !sel.gres.MatchString(node.Gres) {
+		return false
+	}
+	if sel.nodeNames != nil && !sel.nodeNames.MatchString(node.Name) {
+		return false
+	}
+	if sel.nodes != nil {
+		if _, ok := sel.nodes[node.Name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// selectPartitions returns the partitions that pass the partition include and exclude lists
+func (sel *nodeSelector) selectPartitions(partitions []string) []string {
+	if len(sel.partitions) == 0 && len(sel.excludePartitions) == 0 {
+		return partitions
+	}
+
+	var selected []string
+	for _, partition := range partitions {
+		if len(sel.partitions) > 0 && !slices.Contains(sel.partitions, partition) {
+			continue
+		}
+		if slices.Contains(sel.excludePartitions, partition) {
+			continue
+		}
+		selected = append(selected, partition)
+	}
+	return selected
+}
@@ -0,0 +1,265 @@
+package discovery
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/config"
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+func TestMatchesStates(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   []string
+		include []string
+		exclude []string
+		want    bool
+	}{
+		{name: "no filter", state: []string{"DOWN"}, want: true},
+		{name: "included base state", state: []string{"IDLE"}, include: []string{"idle", "mixed"}, want: true},
+		{name: "base state not included", state: []string{"DOWN"}, include: []string{"IDLE"}, want: false},
+		{name: "excluded base state", state: []string{"DOWN"}, exclude: []string{"DOWN"}, want: false},
+		{name: "excluded flag", state: []string{"IDLE", "POWERED_DOWN"}, exclude: []string{"powered_down"}, want: false},
+		{name: "included flag", state: []string{"IDLE", "DRAIN"}, include: []string{"DRAIN"}, want: true},
+		{name: "exclude wins over include", state: []string{"IDLE", "DRAIN"}, include: []string{"IDLE"}, exclude: []string{"DRAIN"}, want: false},
+		{name: "unknown state with include list", state: nil, include: []string{"IDLE"}, want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			node := slurm.Node{Name: "node1", State: tc.state}
+			if got := matchesStates(node, tc.include, tc.exclude); got != tc.want {
+				t.Errorf("matchesStates(%v, %v, %v) = %v, want %v", tc.state, tc.include, tc.exclude, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestService_updateTargets_StateFilter(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	cfg := &config.Config{
+		UpdateInterval:       "5m",
+		DefaultExcludeStates: []string{"DOWN", "POWERED_DOWN"},
+		Jobs: []config.JobConfig{
+			// Uses the global default
+			{Name: "node", Port: 9100},
+			// Overrides the default with its own list
+			{Name: "drained", Port: 9100, IncludeStates: []string{"DRAIN"}, ExcludeStates: []string{}},
+			// Disables the default
+			{Name: "all", Port: 9100, ExcludeStates: []string{}},
+		},
+	}
+	mockClient := &MockSlurmClient{
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			return &slurm.NodeInfoResponse{
+				Nodes: []slurm.Node{
+					{Name: "node1", Address: "10.0.0.1", State: []string{"IDLE"}, Partitions: []string{"compute"}},
+					{Name: "node2", Address: "10.0.0.2", State: []string{"IDLE", "DRAIN"}, Partitions: []string{"compute"}},
+					{Name: "node3", Address: "10.0.0.3", State: []string{"DOWN"}, Partitions: []string{"compute"}},
+					{Name: "node4", Address: "10.0.0.4", State: []string{"IDLE", "POWERED_DOWN"}, Partitions: []string{"compute"}},
+				},
+			}, nil
+		},
+	}
+
+	service, err := NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := service.updateTargets(context.Background()); err != nil {
+		t.Fatalf("updateTargets() error = %v", err)
+	}
+
+	want := map[string][]string{
+		"node":    {"node1", "node2"},
+		"drained": {"node2"},
+		"all":     {"node1", "node2", "node3", "node4"},
+	}
+	for job, wantNodes := range want {
+		targets, _ := service.GetTargets(job)
+		var gotNodes []string
+		for _, target := range targets {
+			gotNodes = append(gotNodes, target.Labels["__meta_slurm_node"])
+		}
+		if !slices.Equal(gotNodes, wantNodes) {
+			t.Errorf("Job %s: got nodes %v, want %v", job, gotNodes, wantNodes)
+		}
+	}
+}
+
+func TestService_updateTargets_NodeSelector(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	nodes := []slurm.Node{
+		{Name: "gpu001", Address: "10.0.1.1", State: []string{"IDLE"}, Partitions: []string{"gpu", "debug"}, Features: []string{"ib", "a100"}, Gres: "gpu:a100:4"},
+		{Name: "gpu002", Address: "10.0.1.2", State: []string{"IDLE"}, Partitions: []string{"gpu"}, ActiveFeatures: []string{"ib"}, Gres: "gpu:h100:8"},
+		{Name: "cpu001", Address: "10.0.2.1", State: []string{"IDLE"}, Partitions: []string{"compute", "debug"}, Features: []string{"ib"}},
+		{Name: "login01", Address: "10.0.3.1", State: []string{"IDLE"}, Partitions: []string{"interactive"}},
+	}
+
+	tests := []struct {
+		name     string
+		selector config.NodeSelector
+		want     []string
+	}{
+		{
+			name:     "no selector",
+			selector: config.NodeSelector{},
+			want:     []string{"gpu001/gpu", "gpu001/debug", "gpu002/gpu", "cpu001/compute", "cpu001/debug", "login01/interactive"},
+		},
+		{
+			name:     "partition include list",
+			selector: config.NodeSelector{Partitions: []string{"gpu"}},
+			want:     []string{"gpu001/gpu", "gpu002/gpu"},
+		},
+		{
+			name:     "partition exclude list",
+			selector: config.NodeSelector{ExcludePartitions: []string{"debug", "interactive"}},
+			want:     []string{"gpu001/gpu", "gpu002/gpu", "cpu001/compute"},
+		},
+		{
+			name:     "required features match available or active features",
+			selector: config.NodeSelector{Features: []string{"ib"}, Partitions: []string{"gpu", "compute"}},
+			want:     []string{"gpu001/gpu", "gpu002/gpu", "cpu001/compute"},
+		},
+		{
+			name:     "all required features",
+			selector: config.NodeSelector{Features: []string{"ib", "a100"}},
+			want:     []string{"gpu001/gpu", "gpu001/debug"},
+		},
+		{
+			name:     "gres regex is anchored",
+			selector: config.NodeSelector{Gres: "gpu:h100:.*"},
+			want:     []string{"gpu002/gpu"},
+		},
+		{
+			name:     "node name regex",
+			selector: config.NodeSelector{NodeNameRegex: "login.*"},
+			want:     []string{"login01/interactive"},
+		},
+		{
+			name:     "hostlist",
+			selector: config.NodeSelector{Nodes: "gpu[001-010],login01", ExcludePartitions: []string{"debug"}},
+			want:     []string{"gpu001/gpu", "gpu002/gpu", "login01/interactive"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{
+				UpdateInterval: "5m",
+				Jobs: []config.JobConfig{
+					{Name: "node", Port: 9100, NodeSelector: tc.selector},
+				},
+			}
+			mockClient := &MockSlurmClient{
+				GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+					return &slurm.NodeInfoResponse{Nodes: nodes}, nil
+				},
+			}
+
+			service, err := NewService(mockClient, cfg, logger)
+			if err != nil {
+				t.Fatalf("Failed to create service: %v", err)
+			}
+			if err := service.updateTargets(context.Background()); err != nil {
+				t.Fatalf("updateTargets() error = %v", err)
+			}
+
+			targets, _ := service.GetTargets("node")
+			var got []string
+			for _, target := range targets {
+				got = append(got, target.Labels["__meta_slurm_node"]+"/"+target.Labels["__meta_slurm_partition"])
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("Got targets %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewService_InvalidNodeSelector(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	for _, selector := range []config.NodeSelector{
+		{Gres: "gpu:("},
+		{NodeNameRegex: "[a-"},
+		{Nodes: "gpu[1-"},
+	} {
+		cfg := &config.Config{
+			UpdateInterval: "5m",
+			Jobs: []config.JobConfig{
+				{Name: "node", Port: 9100, NodeSelector: selector},
+			},
+		}
+		if _, err := NewService(&MockSlurmClient{}, cfg, logger); err == nil {
+			t.Errorf("Expected error for selector %+v, got nil", selector)
+		}
+	}
+}
+
+func TestService_updateTargets_Partitionless(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	cfg := &config.Config{
+		UpdateInterval: "5m",
+		Jobs: []config.JobConfig{
+			{Name: "node", Port: 9100},
+			{Name: "all", Port: 9100, IncludePartitionless: true},
+			{Name: "placeholder", Port: 9100, IncludePartitionless: true, PartitionlessPlaceholder: "none"},
+			{Name: "selected", Port: 9100, IncludePartitionless: true, PartitionlessPlaceholder: "none", NodeSelector: config.NodeSelector{Partitions: []string{"compute"}}},
+		},
+	}
+	mockClient := &MockSlurmClient{
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			return &slurm.NodeInfoResponse{
+				Nodes: []slurm.Node{
+					{Name: "node1", Address: "10.0.0.1", State: []string{"IDLE"}, Partitions: []string{"compute"}},
+					{Name: "login01", Address: "10.0.3.1", State: []string{"IDLE"}},
+				},
+			}, nil
+		},
+	}
+
+	service, err := NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := service.updateTargets(context.Background()); err != nil {
+		t.Fatalf("updateTargets() error = %v", err)
+	}
+
+	want := map[string][]string{
+		"node":        {"node1/compute"},
+		"all":         {"node1/compute", "login01/"},
+		"placeholder": {"node1/compute", "login01/none"},
+		"selected":    {"node1/compute"},
+	}
+	for job, wantTargets := range want {
+		targets, _ := service.GetTargets(job)
+		var got []string
+		for _, target := range targets {
+			got = append(got, target.Labels["__meta_slurm_node"]+"/"+target.Labels["__meta_slurm_partition"])
+		}
+		if !slices.Equal(got, wantTargets) {
+			t.Errorf("Job %s: got targets %v, want %v", job, got, wantTargets)
+		}
+	}
+
+	if got := service.metrics.partitionlessNodes.Load(); got != 1 {
+		t.Errorf("partitionlessNodes = %d, want 1", got)
+	}
+}
@@ -0,0 +1,70 @@
+package discovery
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/config"
+)
+
+// groupTargets merges single-address targets according to the grouping mode
+// of a job. Groups keep the order in which their first target appeared.
+func groupTargets(targets []PrometheusTarget, groupBy string) []PrometheusTarget {
+	var keyOf func(labels map[string]string) (string, map[string]string)
+	switch groupBy {
+	case config.GroupByPartition:
+		keyOf = func(labels map[string]string) (string, map[string]string) {
+			grouped := map[string]string{
+				"__meta_slurm_partition": labels["__meta_slurm_partition"],
+				"__meta_slurm_job":       labels["__meta_slurm_job"],
+			}
+			// Targets of different ports stay in separate groups
+			if name, ok := labels["__meta_slurm_port_name"]; ok {
+				grouped["__meta_slurm_port_name"] = name
+			}
+			// Partition attributes are the same for every target of the group
+			for name, value := range labels {
+				if strings.HasPrefix(name, partitionLabelPrefix) {
+					grouped[name] = value
+				}
+			}
+			return labelSetKey(grouped), grouped
+		}
+	case config.GroupByLabelSet:
+		keyOf = func(labels map[string]string) (string, map[string]string) {
+			delete(labels, "__meta_slurm_node")
+			return labelSetKey(labels), labels
+		}
+	default:
+		return targets
+	}
+
+	var grouped []PrometheusTarget
+	index := make(map[string]int)
+	for _, target := range targets {
+		key, labels := keyOf(target.Labels)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(grouped)
+			grouped = append(grouped, PrometheusTarget{Labels: labels})
+			i = len(grouped) - 1
+		}
+		grouped[i].Targets = append(grouped[i].Targets, target.Targets...)
+	}
+	return grouped
+}
+
+// labelSetKey returns a string that identifies a label set regardless of map order
+func labelSetKey(labels map[string]string) string {
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		// Label values may contain any character, so separate with a byte
+		// that cannot appear in valid UTF-8
+		b.WriteString(name)
+		b.WriteByte(0xff)
+		b.WriteString(labels[name])
+		b.WriteByte(0xff)
+	}
+	return b.String()
+}
@@ -0,0 +1,135 @@
+package discovery
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/config"
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+func TestService_updateTargets_GroupBy(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	nodes := []slurm.Node{
+		{Name: "node1", Address: "10.0.0.1", State: []string{"IDLE"}, Partitions: []string{"compute", "debug"}},
+		{Name: "node2", Address: "10.0.0.2", State: []string{"IDLE"}, Partitions: []string{"compute"}},
+		{Name: "node3", Address: "10.0.0.3", State: []string{"ALLOCATED"}, Partitions: []string{"compute"}},
+	}
+
+	tests := []struct {
+		name     string
+		groupBy  string
+		annotate bool
+		want     []PrometheusTarget
+	}{
+		{
+			name:    "per_partition",
+			groupBy: config.GroupByPartition,
+			want: []PrometheusTarget{
+				{
+					Targets: []string{"10.0.0.1:9100", "10.0.0.2:9100", "10.0.0.3:9100"},
+					Labels:  map[string]string{"__meta_slurm_partition": "compute", "__meta_slurm_job": "node"},
+				},
+				{
+					Targets: []string{"10.0.0.1:9100"},
+					Labels:  map[string]string{"__meta_slurm_partition": "debug", "__meta_slurm_job": "node"},
+				},
+			},
+		},
+		{
+			name:     "per_partition keeps partition attributes",
+			groupBy:  config.GroupByPartition,
+			annotate: true,
+			want: []PrometheusTarget{
+				{
+					Targets: []string{"10.0.0.1:9100", "10.0.0.2:9100", "10.0.0.3:9100"},
+					Labels: map[string]string{
+						"__meta_slurm_partition":          "compute",
+						"__meta_slurm_job":                "node",
+						"__meta_slurm_partition_state":    "UP",
+						"__meta_slurm_partition_qos":      "normal",
+						"__meta_slurm_partition_max_time": "UNLIMITED",
+					},
+				},
+				{
+					Targets: []string{"10.0.0.1:9100"},
+					Labels:  map[string]string{"__meta_slurm_partition": "debug", "__meta_slurm_job": "node"},
+				},
+			},
+		},
+		{
+			name:    "per_label_set",
+			groupBy: config.GroupByLabelSet,
+			want: []PrometheusTarget{
+				{
+					Targets: []string{"10.0.0.1:9100", "10.0.0.2:9100"},
+					Labels:  map[string]string{"__meta_slurm_partition": "compute", "__meta_slurm_job": "node", "__meta_slurm_state": "IDLE"},
+				},
+				{
+					Targets: []string{"10.0.0.1:9100"},
+					Labels:  map[string]string{"__meta_slurm_partition": "debug", "__meta_slurm_job": "node", "__meta_slurm_state": "IDLE"},
+				},
+				{
+					Targets: []string{"10.0.0.3:9100"},
+					Labels:  map[string]string{"__meta_slurm_partition": "compute", "__meta_slurm_job": "node", "__meta_slurm_state": "ALLOCATED"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{
+				UpdateInterval: "5m",
+				Jobs: []config.JobConfig{
+					{Name: "node", Port: 9100, GroupBy: tc.groupBy, AnnotatePartitions: tc.annotate},
+				},
+			}
+			mockClient := &MockSlurmClient{
+				GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+					return &slurm.NodeInfoResponse{Nodes: nodes}, nil
+				},
+				GetPartitionsFunc: func(ctx context.Context) (*slurm.PartitionInfoResponse, error) {
+					return &slurm.PartitionInfoResponse{
+						Partitions: []slurm.Partition{{Name: "compute", State: "UP", QOS: "normal"}},
+					}, nil
+				},
+			}
+
+			service, err := NewService(mockClient, cfg, logger)
+			if err != nil {
+				t.Fatalf("Failed to create service: %v", err)
+			}
+			if err := service.updateTargets(context.Background()); err != nil {
+				t.Fatalf("updateTargets() error = %v", err)
+			}
+
+			got, _ := service.GetTargets("node")
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("GetTargets() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewService_InvalidGroupBy(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	cfg := &config.Config{
+		UpdateInterval: "5m",
+		Jobs: []config.JobConfig{
+			{Name: "node", Port: 9100, GroupBy: "per_rack"},
+		},
+	}
+	if _, err := NewService(&MockSlurmClient{}, cfg, logger); err == nil {
+		t.Error("Expected error for an unknown group_by, got nil")
+	}
+}
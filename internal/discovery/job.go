@@ -0,0 +1,125 @@
+package discovery
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/config"
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+// compiledJob is a config.JobConfig with its selectors and templates compiled
+type compiledJob struct {
+	config.JobConfig
+
+	selector        *nodeSelector
+	portOverrides   []portOverride
+	addressTemplate *template.Template
+	addressFamily   string
+	// metadata is nil unless the job reads Slurm job metadata
+	metadata *jobMetadata
+}
+
+// compileJob validates and compiles the options of a job
+func compileJob(cfg config.JobConfig, addressFamily string) (*compiledJob, error) {
+	switch cfg.Kind {
+	case "", config.JobKindNode, config.JobKindSlurmJob:
+	default:
+		return nil, fmt.Errorf("invalid kind %q", cfg.Kind)
+	}
+	switch cfg.GroupBy {
+	case "", config.GroupByNode, config.GroupByPartition, config.GroupByLabelSet:
+	default:
+		return nil, fmt.Errorf("invalid group_by %q", cfg.GroupBy)
+	}
+
+	j := &compiledJob{JobConfig: cfg, addressFamily: addressFamily}
+	var err error
+	if j.selector, err = newNodeSelector(cfg.NodeSelector); err != nil {
+		return nil, fmt.Errorf("invalid node selector: %w", err)
+	}
+	if j.portOverrides, err = newPortOverrides(cfg); err != nil {
+		return nil, fmt.Errorf("invalid ports: %w", err)
+	}
+	if cfg.Metadata != nil {
+		if cfg.Kind != config.JobKindSlurmJob {
+			return nil, fmt.Errorf("metadata requires kind %q", config.JobKindSlurmJob)
+		}
+		if j.metadata, err = newJobMetadata(*cfg.Metadata); err != nil {
+			return nil, fmt.Errorf("invalid metadata: %w", err)
+		}
+	}
+	if j.addressTemplate, err = cfg.ParseAddressTemplate(); err != nil {
+		return nil, err
+	}
+	if j.addressTemplate != nil {
+		// Catch references to unknown fields before the first refresh.
+		// Errors that depend on the values of real nodes are reported per
+		// target when the template is rendered.
+		if err := j.addressTemplate.Execute(io.Discard, sampleAddressTemplateData); err != nil {
+			return nil, fmt.Errorf("invalid address template: %w", err)
+		}
+	}
+	return j, nil
+}
+
+// addressTemplateData is the data passed to address templates
+type addressTemplateData struct {
+	// Node is the Slurm node, e.g. {{ .Node.Name }}
+	Node slurm.Node
+	// Job is the Slurm job for slurm_job jobs, e.g. {{ .Job.JobID }}
+	Job slurm.Job
+	// Address is the default address: the node address of the preferred
+	// family, or the hostname when the node has no address
+	Address string
+	// Labels are the labels of the target, e.g. {{ index .Labels "__meta_slurm_partition" }}
+	Labels map[string]string
+}
+
+// sampleAddressTemplateData is a filled-in node, job and label set that address
+// templates are checked against when the job is compiled
+var sampleAddressTemplateData = addressTemplateData{
+	Node: slurm.Node{
+		Name:       "sample-node-0001",
+		Address:    "192.0.2.1",
+		Hostname:   "sample-node-0001.example.com",
+		State:      []string{"IDLE"},
+		Partitions: []string{"sample-partition"},
+		Features:   []string{"sample-feature"},
+	},
+	Job: slurm.Job{
+		JobID:     1,
+		Name:      "sample-job",
+		UserName:  "sample-user",
+		Account:   "sample-account",
+		Partition: "sample-partition",
+		State:     []string{"RUNNING"},
+		Nodes:     "sample-node-0001",
+	},
+	Address: "192.0.2.1",
+	Labels: map[string]string{
+		"__meta_slurm_node":      "sample-node-0001",
+		"__meta_slurm_partition": "sample-partition",
+	},
+}
+
+// targetHost returns the host part of a target address.
+// slurmJob is the zero value for node jobs.
+func (j *compiledJob) targetHost(node slurm.Node, slurmJob slurm.Job, labels map[string]string) (string, error) {
+	address := nodeAddress(node, j.addressFamily)
+	if j.addressTemplate == nil {
+		return address, nil
+	}
+
+	var b strings.Builder
+	if err := j.addressTemplate.Execute(&b, addressTemplateData{Node: node, Job: slurmJob, Address: address, Labels: labels}); err != nil {
+		return "", err
+	}
+	host := strings.TrimSpace(b.String())
+	if host == "" {
+		return "", fmt.Errorf("address template rendered an empty host")
+	}
+	return host, nil
+}
@@ -0,0 +1,109 @@
+package discovery
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/config"
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+func TestService_updateTargets_AddressTemplate(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	tests := []struct {
+		name     string
+		template string
+		want     []string
+	}{
+		{
+			name: "default address with hostname fallback",
+			want: []string{"10.0.1.1:9100", "gpu002:9100"},
+		},
+		{
+			name:     "node name",
+			template: "{{ .Node.Name }}-bmc.mgmt.example",
+			want:     []string{"gpu001-bmc.mgmt.example:9100", "gpu002-bmc.mgmt.example:9100"},
+		},
+		{
+			name:     "labels",
+			template: `{{ .Node.Hostname }}.{{ index .Labels "__meta_slurm_partition" }}`,
+			want:     []string{"gpu001.gpu:9100", "gpu002.gpu:9100"},
+		},
+		{
+			name:     "functions depending on node values",
+			template: "{{ slice .Node.Name 0 3 }}.mgmt",
+			want:     []string{"gpu.mgmt:9100", "gpu.mgmt:9100"},
+		},
+		{
+			name:     "empty result skips the target",
+			template: `{{ if .Node.Address }}{{ .Node.Address }}{{ end }}`,
+			want:     []string{"10.0.1.1:9100"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{
+				UpdateInterval: "5m",
+				Jobs: []config.JobConfig{
+					{Name: "node", Port: 9100, AddressTemplate: tc.template},
+				},
+			}
+			mockClient := &MockSlurmClient{
+				GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+					return &slurm.NodeInfoResponse{
+						Nodes: []slurm.Node{
+							{Name: "gpu001", Address: "10.0.1.1", Hostname: "gpu001", State: []string{"IDLE"}, Partitions: []string{"gpu"}},
+							{Name: "gpu002", Hostname: "gpu002", State: []string{"IDLE"}, Partitions: []string{"gpu"}},
+						},
+					}, nil
+				},
+			}
+
+			service, err := NewService(mockClient, cfg, logger)
+			if err != nil {
+				t.Fatalf("Failed to create service: %v", err)
+			}
+			if err := service.updateTargets(context.Background()); err != nil {
+				t.Fatalf("updateTargets() error = %v", err)
+			}
+
+			targets, _ := service.GetTargets("node")
+			var got []string
+			for _, target := range targets {
+				got = append(got, target.Targets...)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("Got targets %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewService_InvalidAddressTemplate(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	for _, tmpl := range []string{
+		"{{ .Node.Name ",
+		"{{ .Node.Rack }}",
+		"{{ .Unknown }}",
+	} {
+		cfg := &config.Config{
+			UpdateInterval: "5m",
+			Jobs: []config.JobConfig{
+				{Name: "node", Port: 9100, AddressTemplate: tmpl},
+			},
+		}
+		if _, err := NewService(&MockSlurmClient{}, cfg, logger); err == nil {
+			t.Errorf("Expected error for address template %q, got nil", tmpl)
+		}
+	}
+}
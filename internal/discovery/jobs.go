@@ -0,0 +1,209 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/config"
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+// runningJob is a running Slurm job with its node list expanded
+type runningJob struct {
+	slurm.Job
+	hosts []string
+}
+
+// needsJobs reports whether any job discovers or annotates running Slurm
+// jobs, so that the job list is only fetched when needed
+func (s *Service) needsJobs() bool {
+	for _, job := range s.jobs {
+		if job.Kind == config.JobKindSlurmJob || job.AnnotateJobs {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchJobs returns the running Slurm jobs
+func (s *Service) fetchJobs(ctx context.Context) ([]runningJob, error) {
+	jobInfo, err := s.slurmClient.GetJobs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jobs from Slurm: %w", err)
+	}
+	if err := s.checkResponse(jobInfo.Errors, jobInfo.Warnings); err != nil {
+		return nil, fmt.Errorf("failed to get jobs from Slurm: %w", err)
+	}
+
+	var running []runningJob
+	for _, job := range jobInfo.Jobs {
+		if !job.IsRunning() {
+			continue
+		}
+		hosts, err := slurm.ExpandHostlist(job.Nodes)
+		if err != nil {
+			s.logger.Warn("Skipping Slurm job with an invalid node list", "job_id", job.JobID, "nodes", job.Nodes, "error", err)
+			continue
+		}
+		running = append(running, runningJob{Job: job, hosts: hosts})
+	}
+	return running, nil
+}
+
+// jobsByNode indexes running Slurm jobs by the names of their nodes
+func jobsByNode(slurmJobs []runningJob) map[string][]slurm.Job {
+	index := make(map[string][]slurm.Job)
+	for _, job := range slurmJobs {
+		for _, host := range job.hosts {
+			index[host] = append(index[host], job.Job)
+		}
+	}
+	return index
+}
+
+// nodeJobLabels returns the labels describing the Slurm jobs running on a node.
+// The singular job labels are only set when a single job uses the node, so
+// that metrics of shared nodes are not attributed to one of their jobs.
+func nodeJobLabels(jobs []slurm.Job) map[string]string {
+	labels := map[string]string{
+		nodeLabelPrefix + "job_count": strconv.Itoa(len(jobs)),
+	}
+	if len(jobs) == 0 {
+		return labels
+	}
+
+	ids := make([]string, 0, len(jobs))
+	var users, accounts []string
+	for _, job := range jobs {
+		ids = append(ids, strconv.FormatInt(job.JobID, 10))
+		if job.UserName != "" {
+			users = append(users, job.UserName)
+		}
+		if job.Account != "" {
+			accounts = append(accounts, job.Account)
+		}
+	}
+	slices.Sort(users)
+	slices.Sort(accounts)
+
+	labels[nodeLabelPrefix+"job_ids"] = strings.Join(ids, ",")
+	labels[nodeLabelPrefix+"job_users"] = strings.Join(slices.Compact(users), ",")
+	labels[nodeLabelPrefix+"job_accounts"] = strings.Join(slices.Compact(accounts), ",")
+	if len(jobs) == 1 {
+		labels[nodeLabelPrefix+"job_id"] = ids[0]
+		labels[nodeLabelPrefix+"job_user"] = jobs[0].UserName
+		labels[nodeLabelPrefix+"job_account"] = jobs[0].Account
+		labels[nodeLabelPrefix+"job_shared"] = "false"
+	} else {
+		labels[nodeLabelPrefix+"job_shared"] = "true"
+	}
+	return labels
+}
+
+// slurmJobTargets returns the targets of a slurm_job job: one target per
+// node allocated to each running Slurm job, labeled with the job details.
+// Jobs whose metadata sets ports are scraped on those ports.
+func (s *Service) slurmJobTargets(job *compiledJob, slurmJobs []runningJob, nodes []slurm.Node, partitions map[string]slurm.Partition) []PrometheusTarget {
+	nodesByName := make(map[string]slurm.Node, len(nodes))
+	for _, node := range nodes {
+		nodesByName[node.Name] = node
+	}
+	includeStates, excludeStates := s.config.StateFilter(job.JobConfig)
+
+	// Malformed metadata is logged once per Slurm job; jobs that are no
+	// longer running are forgotten
+	warned := s.metadataWarned[job.Name]
+	s.metadataWarned[job.Name] = make(map[int64]bool)
+
+	var targets []PrometheusTarget
+	for _, slurmJob := range slurmJobs {
+		jobPartitions := job.upPartitions(job.selector.selectPartitions(splitPartitions(slurmJob.Partition)), partitions)
+		if len(jobPartitions) == 0 {
+			continue
+		}
+
+		jobAttributes := slurmJobLabels(slurmJob.Job)
+		var metadataPorts []config.PortConfig
+		if job.metadata != nil {
+			var metadataLabels map[string]string
+			var errs []error
+			metadataPorts, metadataLabels, errs = job.metadata.parse(slurmJob.Job)
+			if len(errs) > 0 {
+				if !warned[slurmJob.JobID] {
+					s.logger.Warn("Ignoring malformed Slurm job metadata", "job", job.Name, "job_id", slurmJob.JobID, "error", errors.Join(errs...))
+				}
+				s.metadataWarned[job.Name][slurmJob.JobID] = true
+			}
+			maps.Copy(jobAttributes, metadataLabels)
+		}
+		if len(metadataPorts) == 0 && !hasConfiguredPorts(job.JobConfig) {
+			s.logger.Debug("Skipping Slurm job without a port", "job", job.Name, "job_id", slurmJob.JobID)
+			continue
+		}
+
+		for _, host := range slurmJob.hosts {
+			node, ok := nodesByName[host]
+			if !ok {
+				// The node list may lag behind the job list
+				node = slurm.Node{Name: host, Hostname: host}
+			}
+			if !matchesStates(node, includeStates, excludeStates) || !job.selector.matches(node) {
+				continue
+			}
+
+			attributes := nodeLabels(node)
+			maps.Copy(attributes, stateLabels(node.State))
+			maps.Copy(attributes, jobAttributes)
+			attributes = job.withPartitionAttributes(attributes, partitions, jobPartitions[0])
+
+			// Ports from the job metadata take precedence over the configured ones
+			ports := metadataPorts
+			if len(ports) == 0 {
+				ports = targetPorts(job.JobConfig, job.portOverrides, node, jobPartitions[0])
+			}
+			targets = s.appendTargets(targets, job, node, slurmJob.Job, jobPartitions[0], ports, attributes)
+		}
+	}
+	return targets
+}
+
+// slurmJobLabels returns the __meta_slurm_job_* labels describing a Slurm job
+func slurmJobLabels(job slurm.Job) map[string]string {
+	labels := map[string]string{
+		"__meta_slurm_job_id":        strconv.FormatInt(job.JobID, 10),
+		"__meta_slurm_job_name":      job.Name,
+		"__meta_slurm_job_user":      job.UserName,
+		"__meta_slurm_job_account":   job.Account,
+		"__meta_slurm_job_partition": job.Partition,
+		"__meta_slurm_job_qos":       job.QOS,
+	}
+	if job.ArrayJobID != 0 {
+		labels["__meta_slurm_job_array_job_id"] = strconv.FormatInt(job.ArrayJobID, 10)
+	}
+	if job.ArrayTaskID != nil {
+		labels["__meta_slurm_job_array_task_id"] = strconv.FormatInt(*job.ArrayTaskID, 10)
+	}
+	if job.HetJobID != 0 {
+		labels["__meta_slurm_job_het_job_id"] = strconv.FormatInt(job.HetJobID, 10)
+	}
+	if job.HetJobOffset != nil {
+		labels["__meta_slurm_job_het_job_offset"] = strconv.FormatInt(*job.HetJobOffset, 10)
+	}
+	return labels
+}
+
+// splitPartitions splits the comma-separated partition list of a job
+func splitPartitions(partition string) []string {
+	var partitions []string
+	for _, p := range strings.Split(partition, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			partitions = append(partitions, p)
+		}
+	}
+	return partitions
+}
@@ -0,0 +1,382 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/config"
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+func TestService_updateTargets_SlurmJobs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	cfg := &config.Config{
+		UpdateInterval: "5m",
+		Jobs: []config.JobConfig{
+			{Name: "training", Kind: config.JobKindSlurmJob, Port: 8000, NodeSelector: config.NodeSelector{Partitions: []string{"gpu"}}},
+			{Name: "node", Port: 9100},
+		},
+	}
+	taskID := int64(0)
+	mockClient := &MockSlurmClient{
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			return &slurm.NodeInfoResponse{
+				Nodes: []slurm.Node{
+					{Name: "gpu001", Address: "10.0.1.1", State: []string{"ALLOCATED"}, Partitions: []string{"gpu"}},
+					{Name: "gpu002", Address: "10.0.1.2", State: []string{"ALLOCATED"}, Partitions: []string{"gpu"}},
+				},
+			}, nil
+		},
+		GetJobsFunc: func(ctx context.Context) (*slurm.JobInfoResponse, error) {
+			return &slurm.JobInfoResponse{
+				Jobs: []slurm.Job{
+					{JobID: 1001, Name: "train-llm", UserName: "alice", Account: "ml", Partition: "gpu", QOS: "normal", State: []string{"RUNNING"}, Nodes: "gpu[001-002]"},
+					{JobID: 1003, Name: "sweep", UserName: "bob", Account: "physics", Partition: "gpu", QOS: "high", State: []string{"RUNNING"}, Nodes: "gpu003", ArrayJobID: 1002, ArrayTaskID: &taskID},
+					{JobID: 1004, Name: "cpu", UserName: "bob", Account: "physics", Partition: "compute", QOS: "high", State: []string{"RUNNING"}, Nodes: "cpu001"},
+					{JobID: 1020, Name: "queued", UserName: "alice", Account: "ml", Partition: "gpu", QOS: "normal", State: []string{"PENDING"}},
+				},
+			}, nil
+		},
+	}
+
+	service, err := NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := service.updateTargets(context.Background()); err != nil {
+		t.Fatalf("updateTargets() error = %v", err)
+	}
+
+	targets, _ := service.GetTargets("training")
+	var got []string
+	for _, target := range targets {
+		got = append(got, target.Targets[0])
+	}
+	// gpu003 is not in the node list and is scraped by name
+	want := []string{"10.0.1.1:8000", "10.0.1.2:8000", "gpu003:8000"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Got targets %v, want %v", got, want)
+	}
+
+	labels := targets[0].Labels
+	for name, value := range map[string]string{
+		"__meta_slurm_job":           "training",
+		"__meta_slurm_job_id":        "1001",
+		"__meta_slurm_job_name":      "train-llm",
+		"__meta_slurm_job_user":      "alice",
+		"__meta_slurm_job_account":   "ml",
+		"__meta_slurm_job_partition": "gpu",
+		"__meta_slurm_job_qos":       "normal",
+		"__meta_slurm_partition":     "gpu",
+		"__meta_slurm_node":          "gpu001",
+		"__meta_slurm_state":         "ALLOCATED",
+	} {
+		if labels[name] != value {
+			t.Errorf("Label %s = %q, want %q", name, labels[name], value)
+		}
+	}
+	if _, ok := labels["__meta_slurm_job_array_task_id"]; ok {
+		t.Errorf("Non-array job has array labels: %v", labels)
+	}
+
+	arrayLabels := targets[2].Labels
+	if arrayLabels["__meta_slurm_job_array_job_id"] != "1002" || arrayLabels["__meta_slurm_job_array_task_id"] != "0" {
+		t.Errorf("Array job labels are missing: %v", arrayLabels)
+	}
+
+	// Node jobs are unaffected
+	if nodeTargets, _ := service.GetTargets("node"); len(nodeTargets) != 2 {
+		t.Errorf("Expected 2 node targets, got %d", len(nodeTargets))
+	}
+}
+
+func TestService_updateTargets_SlurmJobsSkipped(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	jobsErr := errors.New("connection refused")
+	var jobCalls int
+	mockClient := &MockSlurmClient{
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			return &slurm.NodeInfoResponse{}, nil
+		},
+		GetJobsFunc: func(ctx context.Context) (*slurm.JobInfoResponse, error) {
+			jobCalls++
+			return nil, jobsErr
+		},
+	}
+
+	// Jobs are not fetched without a slurm_job job
+	cfg := &config.Config{
+		UpdateInterval: "5m",
+		Jobs:           []config.JobConfig{{Name: "node", Port: 9100}},
+	}
+	service, err := NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := service.updateTargets(context.Background()); err != nil {
+		t.Fatalf("updateTargets() error = %v", err)
+	}
+	if jobCalls != 0 {
+		t.Errorf("GetJobs was called %d times, want 0", jobCalls)
+	}
+
+	// A failed job fetch fails the refresh
+	cfg.Jobs = append(cfg.Jobs, config.JobConfig{Name: "training", Kind: config.JobKindSlurmJob, Port: 8000})
+	service, err = NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := service.updateTargets(context.Background()); !errors.Is(err, jobsErr) {
+		t.Errorf("updateTargets() error = %v, want %v", err, jobsErr)
+	}
+}
+
+func TestNewService_InvalidKind(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	cfg := &config.Config{
+		UpdateInterval: "5m",
+		Jobs:           []config.JobConfig{{Name: "node", Kind: "reservation", Port: 9100}},
+	}
+	if _, err := NewService(&MockSlurmClient{}, cfg, logger); err == nil {
+		t.Error("Expected error for an unknown kind, got nil")
+	}
+}
+
+func TestService_updateTargets_AnnotateJobs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	cfg := &config.Config{
+		UpdateInterval: "5m",
+		Jobs: []config.JobConfig{
+			{Name: "node", Port: 9100, AnnotateJobs: true},
+			{Name: "plain", Port: 9100},
+		},
+	}
+	mockClient := &MockSlurmClient{
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			return &slurm.NodeInfoResponse{
+				Nodes: []slurm.Node{
+					{Name: "gpu001", Address: "10.0.1.1", State: []string{"ALLOCATED"}, Partitions: []string{"gpu"}},
+					{Name: "gpu002", Address: "10.0.1.2", State: []string{"MIXED"}, Partitions: []string{"gpu"}},
+					{Name: "gpu003", Address: "10.0.1.3", State: []string{"IDLE"}, Partitions: []string{"gpu"}},
+				},
+			}, nil
+		},
+		GetJobsFunc: func(ctx context.Context) (*slurm.JobInfoResponse, error) {
+			return &slurm.JobInfoResponse{
+				Jobs: []slurm.Job{
+					{JobID: 1001, UserName: "alice", Account: "ml", State: []string{"RUNNING"}, Nodes: "gpu[001-002]"},
+					{JobID: 1005, UserName: "bob", Account: "ml", State: []string{"RUNNING"}, Nodes: "gpu002"},
+					{JobID: 1006, UserName: "carol", Account: "physics", State: []string{"RUNNING"}, Nodes: "gpu002"},
+					{JobID: 1020, UserName: "dave", Account: "ml", State: []string{"PENDING"}, Nodes: "gpu003"},
+				},
+			}, nil
+		},
+	}
+
+	service, err := NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := service.updateTargets(context.Background()); err != nil {
+		t.Fatalf("updateTargets() error = %v", err)
+	}
+
+	targets, _ := service.GetTargets("node")
+	if len(targets) != 3 {
+		t.Fatalf("Expected 3 targets, got %d", len(targets))
+	}
+	tests := []struct {
+		node string
+		want map[string]string
+	}{
+		{
+			node: "gpu001",
+			want: map[string]string{
+				"__meta_slurm_node_job_count":    "1",
+				"__meta_slurm_node_job_ids":      "1001",
+				"__meta_slurm_node_job_users":    "alice",
+				"__meta_slurm_node_job_accounts": "ml",
+				"__meta_slurm_node_job_id":       "1001",
+				"__meta_slurm_node_job_user":     "alice",
+				"__meta_slurm_node_job_account":  "ml",
+				"__meta_slurm_node_job_shared":   "false",
+			},
+		},
+		{
+			node: "gpu002",
+			want: map[string]string{
+				"__meta_slurm_node_job_count":    "3",
+				"__meta_slurm_node_job_ids":      "1001,1005,1006",
+				"__meta_slurm_node_job_users":    "alice,bob,carol",
+				"__meta_slurm_node_job_accounts": "ml,physics",
+				"__meta_slurm_node_job_shared":   "true",
+			},
+		},
+		{
+			node: "gpu003",
+			want: map[string]string{
+				"__meta_slurm_node_job_count": "0",
+			},
+		},
+	}
+	for i, tc := range tests {
+		labels := targets[i].Labels
+		if labels["__meta_slurm_node"] != tc.node {
+			t.Fatalf("Target %d is node %q, want %q", i, labels["__meta_slurm_node"], tc.node)
+		}
+		got := make(map[string]string)
+		for name, value := range labels {
+			if strings.HasPrefix(name, "__meta_slurm_node_job_") {
+				got[name] = value
+			}
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Job labels of %s = %v, want %v", tc.node, got, tc.want)
+		}
+	}
+
+	// Jobs that do not annotate their targets have no job labels
+	plain, _ := service.GetTargets("plain")
+	if _, ok := plain[0].Labels["__meta_slurm_node_job_count"]; ok {
+		t.Errorf("Unexpected job labels: %v", plain[0].Labels)
+	}
+}
+
+func TestService_updateTargets_SlurmJobMetadata(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	mockClient := &MockSlurmClient{
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			return &slurm.NodeInfoResponse{}, nil
+		},
+		GetJobsFunc: func(ctx context.Context) (*slurm.JobInfoResponse, error) {
+			return &slurm.JobInfoResponse{
+				Jobs: []slurm.Job{
+					{JobID: 1001, Partition: "gpu", State: []string{"RUNNING"}, Nodes: "gpu001", Comment: "prom_port=8000 team=nlp"},
+					{JobID: 1002, Partition: "gpu", State: []string{"RUNNING"}, Nodes: "gpu002", Comment: "no metadata"},
+				},
+			}, nil
+		},
+	}
+
+	tests := []struct {
+		name string
+		job  config.JobConfig
+		want []string
+	}{
+		{
+			name: "metadata port only",
+			job:  config.JobConfig{Name: "user", Kind: config.JobKindSlurmJob, Metadata: &config.JobMetadata{}},
+			want: []string{"gpu001:8000"},
+		},
+		{
+			name: "configured port as fallback",
+			job:  config.JobConfig{Name: "user", Kind: config.JobKindSlurmJob, Port: 9100, Metadata: &config.JobMetadata{}},
+			want: []string{"gpu001:8000", "gpu002:9100"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{UpdateInterval: "5m", Jobs: []config.JobConfig{tc.job}}
+			service, err := NewService(mockClient, cfg, logger)
+			if err != nil {
+				t.Fatalf("Failed to create service: %v", err)
+			}
+			if err := service.updateTargets(context.Background()); err != nil {
+				t.Fatalf("updateTargets() error = %v", err)
+			}
+
+			targets, _ := service.GetTargets("user")
+			var got []string
+			for _, target := range targets {
+				got = append(got, target.Targets[0])
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("Got targets %v, want %v", got, tc.want)
+			}
+			if targets[0].Labels["__meta_slurm_job_metadata_team"] != "nlp" {
+				t.Errorf("Metadata label is missing: %v", targets[0].Labels)
+			}
+		})
+	}
+}
+
+func TestNewService_InvalidMetadata(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	jobs := []config.JobConfig{
+		{Name: "node", Port: 9100, Metadata: &config.JobMetadata{}},
+		{Name: "user", Kind: config.JobKindSlurmJob, Metadata: &config.JobMetadata{Fields: []string{"environment"}}},
+		{Name: "user", Kind: config.JobKindSlurmJob},
+	}
+	for _, job := range jobs {
+		cfg := &config.Config{UpdateInterval: "5m", Jobs: []config.JobConfig{job}}
+		if _, err := NewService(&MockSlurmClient{}, cfg, logger); err == nil {
+			t.Errorf("Expected error for job %+v, got nil", job)
+		}
+	}
+}
+
+func TestService_updateTargets_SlurmJobMetadataWarnings(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+	}))
+
+	jobs := []slurm.Job{
+		{JobID: 1001, Partition: "gpu", State: []string{"RUNNING"}, Nodes: "gpu001", Comment: "ablation run 3 prom_port=http"},
+		{JobID: 1002, Partition: "gpu", State: []string{"RUNNING"}, Nodes: "gpu002", Comment: "ablation run 4"},
+	}
+	mockClient := &MockSlurmClient{
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			return &slurm.NodeInfoResponse{}, nil
+		},
+		GetJobsFunc: func(ctx context.Context) (*slurm.JobInfoResponse, error) {
+			return &slurm.JobInfoResponse{Jobs: jobs}, nil
+		},
+	}
+	cfg := &config.Config{
+		UpdateInterval: "5m",
+		Jobs:           []config.JobConfig{{Name: "user", Kind: config.JobKindSlurmJob, Port: 9100, Metadata: &config.JobMetadata{}}},
+	}
+	service, err := NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	// Free text is not reported, and an invalid port is reported once
+	for i := 0; i < 3; i++ {
+		if err := service.updateTargets(context.Background()); err != nil {
+			t.Fatalf("updateTargets() error = %v", err)
+		}
+	}
+	if got := strings.Count(logs.String(), "Ignoring malformed Slurm job metadata"); got != 1 {
+		t.Errorf("Got %d metadata warnings, want 1:\n%s", got, logs.String())
+	}
+	if !strings.Contains(logs.String(), "job_id=1001") {
+		t.Errorf("Warning does not name the Slurm job:\n%s", logs.String())
+	}
+}
@@ -0,0 +1,98 @@
+package discovery
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+// Prefix of the labels describing node attributes
+const nodeLabelPrefix = "__meta_slurm_node_"
+
+// nodeLabels returns the __meta_slurm_node_* labels describing the attributes of a node.
+// Attributes that Slurm did not report are omitted.
+func nodeLabels(node slurm.Node) map[string]string {
+	labels := make(map[string]string)
+
+	setString := func(name, value string) {
+		if value != "" {
+			labels[nodeLabelPrefix+name] = value
+		}
+	}
+	setNumber := func(name string, value int64) {
+		if value != 0 {
+			labels[nodeLabelPrefix+name] = strconv.FormatInt(value, 10)
+		}
+	}
+
+	setString("features", strings.Join(node.Features, ","))
+	setString("active_features", strings.Join(node.ActiveFeatures, ","))
+	setString("gres", node.Gres)
+	setString("gres_used", node.GresUsed)
+	setString("architecture", node.Architecture)
+	setString("operating_system", node.OperatingSystem)
+	setNumber("cpus", node.CPUs)
+	setNumber("real_memory", node.RealMemory)
+	setNumber("sockets", node.Sockets)
+	setNumber("cores", node.Cores)
+	setNumber("threads", node.Threads)
+	setString("cluster_name", node.ClusterName)
+	setString("reason", node.Reason)
+	setString("comment", node.Comment)
+	setString("extra", node.Extra)
+	setString("instance_type", node.InstanceType)
+	setString("slurmd_version", node.SlurmdVersion)
+
+	// One presence label per feature, so that a single keep rule can match it
+	for _, features := range [][]string{node.Features, node.ActiveFeatures} {
+		for _, feature := range features {
+			labels[nodeLabelPrefix+"feature_"+sanitizeLabelName(feature)] = "true"
+		}
+	}
+
+	return labels
+}
+
+// stateLabels returns the labels describing the base state and state flags of a node.
+// The base state is "unknown" when Slurm did not report one.
+func stateLabels(state []string) map[string]string {
+	labels := map[string]string{
+		"__meta_slurm_state": "unknown",
+	}
+	if len(state) == 0 {
+		return labels
+	}
+
+	labels["__meta_slurm_state"] = state[0]
+	if flags := state[1:]; len(flags) > 0 {
+		labels["__meta_slurm_state_flags"] = strings.Join(flags, ",")
+		for _, flag := range flags {
+			labels["__meta_slurm_state_flag_"+strings.ToLower(sanitizeLabelName(flag))] = "true"
+		}
+	}
+	return labels
+}
+
+// partitionLabels returns the labels listing all partitions of a node, used
+// when a node is emitted once instead of once per partition
+func partitionLabels(partitions []string) map[string]string {
+	labels := map[string]string{
+		"__meta_slurm_partitions": strings.Join(partitions, ","),
+	}
+	for _, partition := range partitions {
+		labels["__meta_slurm_partitionpresent_"+sanitizeLabelName(partition)] = "true"
+	}
+	return labels
+}
+
+// sanitizeLabelName replaces characters that are not valid in a Prometheus
+// label name with underscores, like Prometheus' own service discoveries do
+func sanitizeLabelName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,228 @@
+package discovery
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/config"
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+func TestNodeLabels(t *testing.T) {
+	tests := []struct {
+		name string
+		node slurm.Node
+		want map[string]string
+	}{
+		{
+			name: "all attributes",
+			node: slurm.Node{
+				Name:            "gpu001",
+				Features:        []string{"ib", "a100"},
+				ActiveFeatures:  []string{"ib"},
+				Gres:            "gpu:a100:4",
+				GresUsed:        "gpu:a100:2(IDX:0-1)",
+				CPUs:            64,
+				Sockets:         2,
+				Cores:           16,
+				Threads:         2,
+				RealMemory:      256000,
+				Architecture:    "x86_64",
+				OperatingSystem: "Linux 5.14.0",
+				ClusterName:     "cluster1",
+				Reason:          "nvlink errors",
+				Comment:         "rack 12",
+				Extra:           "owner=ml",
+				InstanceType:    "p4d.24xlarge",
+				SlurmdVersion:   "24.05.3",
+			},
+			want: map[string]string{
+				"__meta_slurm_node_features":         "ib,a100",
+				"__meta_slurm_node_active_features":  "ib",
+				"__meta_slurm_node_gres":             "gpu:a100:4",
+				"__meta_slurm_node_gres_used":        "gpu:a100:2(IDX:0-1)",
+				"__meta_slurm_node_architecture":     "x86_64",
+				"__meta_slurm_node_operating_system": "Linux 5.14.0",
+				"__meta_slurm_node_cpus":             "64",
+				"__meta_slurm_node_real_memory":      "256000",
+				"__meta_slurm_node_sockets":          "2",
+				"__meta_slurm_node_cores":            "16",
+				"__meta_slurm_node_threads":          "2",
+				"__meta_slurm_node_cluster_name":     "cluster1",
+				"__meta_slurm_node_reason":           "nvlink errors",
+				"__meta_slurm_node_comment":          "rack 12",
+				"__meta_slurm_node_extra":            "owner=ml",
+				"__meta_slurm_node_instance_type":    "p4d.24xlarge",
+				"__meta_slurm_node_slurmd_version":   "24.05.3",
+				"__meta_slurm_node_feature_ib":       "true",
+				"__meta_slurm_node_feature_a100":     "true",
+			},
+		},
+		{
+			name: "feature names are sanitized",
+			node: slurm.Node{Name: "gpu002", Features: []string{"nvme"}, ActiveFeatures: []string{"gpu-h100", "rack.12"}},
+			want: map[string]string{
+				"__meta_slurm_node_features":         "nvme",
+				"__meta_slurm_node_active_features":  "gpu-h100,rack.12",
+				"__meta_slurm_node_feature_nvme":     "true",
+				"__meta_slurm_node_feature_gpu_h100": "true",
+				"__meta_slurm_node_feature_rack_12":  "true",
+			},
+		},
+		{
+			name: "unreported attributes are omitted",
+			node: slurm.Node{Name: "cpu001", CPUs: 64, Architecture: "x86_64"},
+			want: map[string]string{
+				"__meta_slurm_node_cpus":         "64",
+				"__meta_slurm_node_architecture": "x86_64",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := nodeLabels(tc.node); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("nodeLabels() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStateLabels(t *testing.T) {
+	tests := []struct {
+		name  string
+		state []string
+		want  map[string]string
+	}{
+		{
+			name:  "unknown state",
+			state: nil,
+			want:  map[string]string{"__meta_slurm_state": "unknown"},
+		},
+		{
+			name:  "base state only",
+			state: []string{"IDLE"},
+			want:  map[string]string{"__meta_slurm_state": "IDLE"},
+		},
+		{
+			name:  "base state with flags",
+			state: []string{"IDLE", "DRAIN", "NOT_RESPONDING"},
+			want: map[string]string{
+				"__meta_slurm_state":                     "IDLE",
+				"__meta_slurm_state_flags":               "DRAIN,NOT_RESPONDING",
+				"__meta_slurm_state_flag_drain":          "true",
+				"__meta_slurm_state_flag_not_responding": "true",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := stateLabels(tc.state); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("stateLabels() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestService_updateTargets_NodeLabels(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	cfg := &config.Config{
+		UpdateInterval: "5m",
+		Jobs: []config.JobConfig{
+			{Name: "node", Port: 9100},
+		},
+	}
+	mockClient := &MockSlurmClient{
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			return &slurm.NodeInfoResponse{
+				Nodes: []slurm.Node{
+					{Name: "gpu001", Address: "10.0.1.1", State: []string{"IDLE", "DRAIN"}, Partitions: []string{"gpu", "debug"}, Gres: "gpu:a100:4", CPUs: 64},
+				},
+			}, nil
+		},
+	}
+
+	service, err := NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := service.updateTargets(context.Background()); err != nil {
+		t.Fatalf("updateTargets() error = %v", err)
+	}
+
+	targets, _ := service.GetTargets("node")
+	if len(targets) != 2 {
+		t.Fatalf("Expected 2 targets, got %d", len(targets))
+	}
+	for _, target := range targets {
+		if target.Labels["__meta_slurm_node_gres"] != "gpu:a100:4" || target.Labels["__meta_slurm_node_cpus"] != "64" {
+			t.Errorf("Target %v is missing node attribute labels: %v", target.Targets, target.Labels)
+		}
+		if target.Labels["__meta_slurm_state"] != "IDLE" || target.Labels["__meta_slurm_state_flag_drain"] != "true" {
+			t.Errorf("Target %v is missing state labels: %v", target.Targets, target.Labels)
+		}
+	}
+	// Each partition target owns its label set
+	if targets[0].Labels["__meta_slurm_partition"] == targets[1].Labels["__meta_slurm_partition"] {
+		t.Errorf("Targets share the partition label: %v", targets)
+	}
+}
+
+func TestService_updateTargets_DedupeNodes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	cfg := &config.Config{
+		UpdateInterval: "5m",
+		Jobs: []config.JobConfig{
+			{Name: "node", Port: 9100, DedupeNodes: true},
+			{Name: "dcgm", Port: 9400},
+		},
+	}
+	mockClient := &MockSlurmClient{
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			return &slurm.NodeInfoResponse{
+				Nodes: []slurm.Node{
+					{Name: "node1", Address: "10.0.0.1", State: []string{"IDLE"}, Partitions: []string{"compute", "debug-short"}},
+				},
+			}, nil
+		},
+	}
+
+	service, err := NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := service.updateTargets(context.Background()); err != nil {
+		t.Fatalf("updateTargets() error = %v", err)
+	}
+
+	targets, _ := service.GetTargets("node")
+	if len(targets) != 1 {
+		t.Fatalf("Expected 1 deduplicated target, got %d: %+v", len(targets), targets)
+	}
+	labels := targets[0].Labels
+	for name, want := range map[string]string{
+		"__meta_slurm_partition":                    "compute",
+		"__meta_slurm_partitions":                   "compute,debug-short",
+		"__meta_slurm_partitionpresent_compute":     "true",
+		"__meta_slurm_partitionpresent_debug_short": "true",
+	} {
+		if labels[name] != want {
+			t.Errorf("Label %s = %q, want %q", name, labels[name], want)
+		}
+	}
+
+	// Jobs without deduplication still get one target per partition
+	if targets, _ := service.GetTargets("dcgm"); len(targets) != 2 {
+		t.Errorf("Expected 2 targets without deduplication, got %d", len(targets))
+	}
+}
@@ -0,0 +1,118 @@
+package discovery
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/config"
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+// Prefix of the labels read from the metadata of Slurm jobs
+const jobMetadataLabelPrefix = "__meta_slurm_job_metadata_"
+
+// defaultMetadataPortKey is the key holding the exporter port when port_key is unset
+const defaultMetadataPortKey = "prom_port"
+
+// jobMetadata is the compiled form of a config.JobMetadata
+type jobMetadata struct {
+	fields    []string
+	portKey   string
+	separator string
+	// labelKeys is nil when every key other than the port key is a label
+	labelKeys map[string]bool
+}
+
+// newJobMetadata validates a metadata configuration and applies its defaults
+func newJobMetadata(cfg config.JobMetadata) (*jobMetadata, error) {
+	m := &jobMetadata{fields: cfg.Fields, portKey: cfg.PortKey, separator: cfg.Separator}
+	if len(m.fields) == 0 {
+		m.fields = []string{config.JobFieldComment}
+	}
+	for _, field := range m.fields {
+		switch field {
+		case config.JobFieldComment, config.JobFieldAdminComment, config.JobFieldExtra:
+		default:
+			return nil, fmt.Errorf("invalid field %q", field)
+		}
+	}
+	if m.portKey == "" {
+		m.portKey = defaultMetadataPortKey
+	}
+	if len(cfg.LabelKeys) > 0 {
+		m.labelKeys = make(map[string]bool, len(cfg.LabelKeys))
+		for _, key := range cfg.LabelKeys {
+			m.labelKeys[key] = true
+		}
+	}
+	return m, nil
+}
+
+// parse returns the ports and labels found in the metadata fields of a Slurm
+// job. Malformed entries are skipped and reported in errs. With the default
+// separator, words without "=" are free text and silently ignored.
+func (m *jobMetadata) parse(job slurm.Job) (ports []config.PortConfig, labels map[string]string, errs []error) {
+	labels = make(map[string]string)
+	for _, field := range m.fields {
+		for _, entry := range m.split(jobField(job, field)) {
+			key, value, ok := strings.Cut(entry, "=")
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if !ok && m.separator == "" {
+				// Words of a free-text comment are not pairs
+				continue
+			}
+			if !ok || key == "" {
+				errs = append(errs, fmt.Errorf("%s: malformed entry %q", field, entry))
+				continue
+			}
+
+			if key == m.portKey {
+				port, err := strconv.Atoi(value)
+				if err != nil || port < 1 || port > 65535 {
+					errs = append(errs, fmt.Errorf("%s: invalid port %q", field, value))
+					continue
+				}
+				if !slices.Contains(ports, config.PortConfig{Port: port}) {
+					ports = append(ports, config.PortConfig{Port: port})
+				}
+				continue
+			}
+			if m.labelKeys == nil || m.labelKeys[key] {
+				labels[jobMetadataLabelPrefix+sanitizeLabelName(key)] = value
+			}
+		}
+	}
+	return ports, labels, errs
+}
+
+// split splits a field into its entries, ignoring empty ones
+func (m *jobMetadata) split(value string) []string {
+	if m.separator == "" {
+		return strings.FieldsFunc(value, func(r rune) bool {
+			return unicode.IsSpace(r) || r == ',' || r == ';'
+		})
+	}
+
+	var entries []string
+	for _, entry := range strings.Split(value, m.separator) {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// jobField returns the value of a free-form field of a Slurm job
+func jobField(job slurm.Job, field string) string {
+	switch field {
+	case config.JobFieldAdminComment:
+		return job.AdminComment
+	case config.JobFieldExtra:
+		return job.Extra
+	default:
+		return job.Comment
+	}
+}
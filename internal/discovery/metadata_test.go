@@ -0,0 +1,94 @@
+package discovery
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/config"
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+func TestJobMetadata_parse(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        config.JobMetadata
+		job        slurm.Job
+		wantPorts  []config.PortConfig
+		wantLabels map[string]string
+		wantErrs   int
+	}{
+		{
+			name:       "port and labels in the comment",
+			job:        slurm.Job{Comment: "prom_port=8000 team=nlp,stage=dev"},
+			wantPorts:  []config.PortConfig{{Port: 8000}},
+			wantLabels: map[string]string{"__meta_slurm_job_metadata_team": "nlp", "__meta_slurm_job_metadata_stage": "dev"},
+		},
+		{
+			name:       "other fields are ignored by default",
+			job:        slurm.Job{Extra: "prom_port=8000"},
+			wantLabels: map[string]string{},
+		},
+		{
+			name:       "several fields and repeated ports",
+			cfg:        config.JobMetadata{Fields: []string{"extra", "admin_comment"}},
+			job:        slurm.Job{Extra: "prom_port=8000;prom_port=8001 app=a", AdminComment: "prom_port=8000 app=b"},
+			wantPorts:  []config.PortConfig{{Port: 8000}, {Port: 8001}},
+			wantLabels: map[string]string{"__meta_slurm_job_metadata_app": "b"},
+		},
+		{
+			name:       "custom port key, separator and label keys",
+			cfg:        config.JobMetadata{PortKey: "metrics-port", Separator: "|", LabelKeys: []string{"team"}},
+			job:        slurm.Job{Comment: "metrics-port=9000 | team=ml ops | app=x"},
+			wantPorts:  []config.PortConfig{{Port: 9000}},
+			wantLabels: map[string]string{"__meta_slurm_job_metadata_team": "ml ops"},
+		},
+		{
+			name:       "malformed entries are skipped",
+			job:        slurm.Job{Comment: "prom_port=http prom_port=70000 =x team=nlp"},
+			wantLabels: map[string]string{"__meta_slurm_job_metadata_team": "nlp"},
+			wantErrs:   3,
+		},
+		{
+			name:       "free text is ignored with the default separator",
+			job:        slurm.Job{Comment: "ablation run 3 team=nlp"},
+			wantLabels: map[string]string{"__meta_slurm_job_metadata_team": "nlp"},
+		},
+		{
+			name:       "entries without a value are malformed with a custom separator",
+			cfg:        config.JobMetadata{Separator: ";"},
+			job:        slurm.Job{Comment: "ablation run 3;team=nlp"},
+			wantLabels: map[string]string{"__meta_slurm_job_metadata_team": "nlp"},
+			wantErrs:   1,
+		},
+		{
+			name:       "keys are sanitized",
+			job:        slurm.Job{Comment: "app.version=1.2"},
+			wantLabels: map[string]string{"__meta_slurm_job_metadata_app_version": "1.2"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := newJobMetadata(tc.cfg)
+			if err != nil {
+				t.Fatalf("newJobMetadata() error = %v", err)
+			}
+			ports, labels, errs := m.parse(tc.job)
+			if !reflect.DeepEqual(ports, tc.wantPorts) {
+				t.Errorf("ports = %v, want %v", ports, tc.wantPorts)
+			}
+			if !reflect.DeepEqual(labels, tc.wantLabels) {
+				t.Errorf("labels = %v, want %v", labels, tc.wantLabels)
+			}
+			if len(errs) != tc.wantErrs {
+				t.Errorf("got %d errors %v, want %d", len(errs), errs, tc.wantErrs)
+			}
+		})
+	}
+}
+
+func TestNewJobMetadata_InvalidField(t *testing.T) {
+	if _, err := newJobMetadata(config.JobMetadata{Fields: []string{"environment"}}); err == nil {
+		t.Error("Expected error for an unknown field, got nil")
+	}
+}
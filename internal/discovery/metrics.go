@@ -0,0 +1,50 @@
+package discovery
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// metrics holds the self-monitoring metrics of the service.
+// They are exposed in the Prometheus text exposition format.
+type metrics struct {
+	refreshes        atomic.Uint64
+	refreshFailures  atomic.Uint64
+	slurmAPIErrors   atomic.Uint64
+	slurmAPIWarnings atomic.Uint64
+
+	targetDropsRejected atomic.Uint64
+
+	partitionlessNodes atomic.Uint64
+}
+
+// write writes all metrics in the Prometheus text exposition format
+func (m *metrics) write(w io.Writer) {
+	writeMetric(w, "prometheus_slurm_sd_refreshes_total", "counter",
+		"Total number of target refreshes.", m.refreshes.Load())
+	writeMetric(w, "prometheus_slurm_sd_refresh_failures_total", "counter",
+		"Total number of failed target refreshes.", m.refreshFailures.Load())
+	writeMetric(w, "prometheus_slurm_sd_slurm_api_errors_total", "counter",
+		"Total number of entries in the errors array of Slurm REST API responses.", m.slurmAPIErrors.Load())
+	writeMetric(w, "prometheus_slurm_sd_slurm_api_warnings_total", "counter",
+		"Total number of entries in the warnings array of Slurm REST API responses.", m.slurmAPIWarnings.Load())
+	writeMetric(w, "prometheus_slurm_sd_target_drops_rejected_total", "counter",
+		"Total number of target updates rejected because the target count dropped beyond the limit.", m.targetDropsRejected.Load())
+	writeMetric(w, "prometheus_slurm_sd_partitionless_nodes", "gauge",
+		"Number of nodes that belong to no partition in the last fetched node list.", m.partitionlessNodes.Load())
+}
+
+// writeMetric writes a single unlabeled metric with its metadata
+func writeMetric(w io.Writer, name, metricType, help string, value uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", name, help, name, metricType, name, value)
+}
+
+// MetricsHandler is the handler for the self-monitoring metrics endpoint
+func (s *Service) MetricsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		s.metrics.write(w)
+	}
+}
@@ -0,0 +1,82 @@
+package discovery
+
+import (
+	"context"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/config"
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+func TestService_SlurmErrorsAndWarnings(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	cfg := &config.Config{
+		UpdateInterval: "5m",
+		Jobs: []config.JobConfig{
+			{Name: "node", Port: 9100},
+		},
+	}
+
+	resp := &slurm.NodeInfoResponse{
+		Nodes: []slurm.Node{
+			{Name: "node1", Address: "10.0.0.1", State: []string{"IDLE"}, Partitions: []string{"compute"}},
+		},
+		Warnings: []slurm.Warning{
+			{Description: "node list truncated", Source: "slurm_load_node"},
+		},
+	}
+	mockClient := &MockSlurmClient{
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			return resp, nil
+		},
+	}
+
+	service, err := NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	// Warnings do not fail the refresh
+	if err := service.refresh(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Errors fail the refresh and keep the previous targets
+	resp = &slurm.NodeInfoResponse{
+		Nodes: []slurm.Node{},
+		Errors: []slurm.Error{
+			{Description: "Unable to query nodes", Error: "Invalid user", ErrorNumber: 2002, Source: "slurm_load_node"},
+			{Description: "Retry later", ErrorNumber: 9000},
+		},
+	}
+	if err := service.refresh(context.Background()); err == nil {
+		t.Fatal("Expected error for a response with errors, got nil")
+	}
+	if targets, _ := service.GetTargets("node"); len(targets) != 1 {
+		t.Errorf("Expected previous targets to be kept, got %+v", targets)
+	}
+
+	rr := httptest.NewRecorder()
+	service.MetricsHandler()(rr, httptest.NewRequest("GET", "/metrics", nil))
+	body := rr.Body.String()
+	for _, want := range []string{
+		"prometheus_slurm_sd_refreshes_total 2\n",
+		"prometheus_slurm_sd_refresh_failures_total 1\n",
+		"prometheus_slurm_sd_slurm_api_errors_total 2\n",
+		"prometheus_slurm_sd_slurm_api_warnings_total 1\n",
+		"# TYPE prometheus_slurm_sd_refreshes_total counter\n",
+		"prometheus_slurm_sd_partitionless_nodes 0\n",
+		"# TYPE prometheus_slurm_sd_partitionless_nodes gauge\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Metrics output missing %q:\n%s", want, body)
+		}
+	}
+}
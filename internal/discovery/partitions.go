@@ -0,0 +1,101 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+// Prefix of the labels describing partition attributes
+const partitionLabelPrefix = "__meta_slurm_partition_"
+
+// needsPartitions reports whether any job uses partition attributes, so
+// that the partition list is only fetched when needed
+func (s *Service) needsPartitions() bool {
+	for _, job := range s.jobs {
+		if job.AnnotatePartitions || job.OnlyUpPartitions {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchPartitions returns the Slurm partitions by name
+func (s *Service) fetchPartitions(ctx context.Context) (map[string]slurm.Partition, error) {
+	partitionInfo, err := s.slurmClient.GetPartitions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get partitions from Slurm: %w", err)
+	}
+	if err := s.checkResponse(partitionInfo.Errors, partitionInfo.Warnings); err != nil {
+		return nil, fmt.Errorf("failed to get partitions from Slurm: %w", err)
+	}
+
+	partitions := make(map[string]slurm.Partition, len(partitionInfo.Partitions))
+	for _, partition := range partitionInfo.Partitions {
+		partitions[partition.Name] = partition
+	}
+	return partitions, nil
+}
+
+// upPartitions drops the partitions that are known not to be UP when the job
+// asks for it. Partitions missing from the partition list, such as the
+// partitionless placeholder, are kept.
+func (j *compiledJob) upPartitions(names []string, partitions map[string]slurm.Partition) []string {
+	if !j.OnlyUpPartitions {
+		return names
+	}
+
+	var up []string
+	for _, name := range names {
+		if partition, ok := partitions[name]; !ok || partition.IsUp() {
+			up = append(up, name)
+		}
+	}
+	return up
+}
+
+// withPartitionAttributes returns the attributes of a target completed with
+// the labels describing its partition, when the job asks for them
+func (j *compiledJob) withPartitionAttributes(attributes map[string]string, partitions map[string]slurm.Partition, name string) map[string]string {
+	partition, ok := partitions[name]
+	if !j.AnnotatePartitions || !ok {
+		return attributes
+	}
+	attributes = maps.Clone(attributes)
+	maps.Copy(attributes, partitionAttributeLabels(partition))
+	return attributes
+}
+
+// partitionAttributeLabels returns the __meta_slurm_partition_* labels
+// describing the attributes of a partition. Unset attributes are omitted,
+// and an unlimited maximum time is reported as "UNLIMITED".
+func partitionAttributeLabels(partition slurm.Partition) map[string]string {
+	labels := make(map[string]string)
+
+	setString := func(name, value string) {
+		if value != "" {
+			labels[partitionLabelPrefix+name] = value
+		}
+	}
+	setNumber := func(name string, value int64) {
+		if value != 0 {
+			labels[partitionLabelPrefix+name] = strconv.FormatInt(value, 10)
+		}
+	}
+
+	setString("state", partition.State)
+	setString("qos", partition.QOS)
+	if partition.MaxTime != nil {
+		labels[partitionLabelPrefix+"max_time"] = strconv.FormatInt(*partition.MaxTime, 10)
+	} else {
+		labels[partitionLabelPrefix+"max_time"] = "UNLIMITED"
+	}
+	setNumber("default_memory_per_cpu", partition.DefaultMemoryPerCPU)
+	setNumber("default_memory_per_node", partition.DefaultMemoryPerNode)
+	setNumber("total_nodes", partition.TotalNodes)
+	setString("billing_weights", partition.BillingWeights)
+	return labels
+}
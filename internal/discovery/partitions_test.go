@@ -0,0 +1,177 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/config"
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+func TestPartitionAttributeLabels(t *testing.T) {
+	maxTime := int64(1440)
+	tests := []struct {
+		name      string
+		partition slurm.Partition
+		want      map[string]string
+	}{
+		{
+			name: "all attributes",
+			partition: slurm.Partition{
+				Name: "compute", State: "UP", QOS: "normal", MaxTime: &maxTime,
+				DefaultMemoryPerCPU: 4000, TotalNodes: 4, BillingWeights: "CPU=1.0,Mem=0.25G",
+			},
+			want: map[string]string{
+				"__meta_slurm_partition_state":                  "UP",
+				"__meta_slurm_partition_qos":                    "normal",
+				"__meta_slurm_partition_max_time":               "1440",
+				"__meta_slurm_partition_default_memory_per_cpu": "4000",
+				"__meta_slurm_partition_total_nodes":            "4",
+				"__meta_slurm_partition_billing_weights":        "CPU=1.0,Mem=0.25G",
+			},
+		},
+		{
+			name:      "unlimited time and unset attributes",
+			partition: slurm.Partition{Name: "gpu", State: "DRAIN", DefaultMemoryPerNode: 64000},
+			want: map[string]string{
+				"__meta_slurm_partition_state":                   "DRAIN",
+				"__meta_slurm_partition_max_time":                "UNLIMITED",
+				"__meta_slurm_partition_default_memory_per_node": "64000",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := partitionAttributeLabels(tc.partition); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("partitionAttributeLabels() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestService_updateTargets_Partitions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	var partitionCalls int
+	mockClient := &MockSlurmClient{
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			return &slurm.NodeInfoResponse{
+				Nodes: []slurm.Node{
+					{Name: "cpu001", Address: "10.0.0.1", State: []string{"IDLE"}, Partitions: []string{"compute", "maint"}},
+					{Name: "cpu002", Address: "10.0.0.2", State: []string{"IDLE"}, Partitions: []string{"maint"}},
+					{Name: "login01", Address: "10.0.9.1", State: []string{"IDLE"}},
+				},
+			}, nil
+		},
+		GetJobsFunc: func(ctx context.Context) (*slurm.JobInfoResponse, error) {
+			return &slurm.JobInfoResponse{
+				Jobs: []slurm.Job{
+					{JobID: 1001, Partition: "compute", State: []string{"RUNNING"}, Nodes: "cpu001"},
+					{JobID: 1002, Partition: "maint", State: []string{"RUNNING"}, Nodes: "cpu002"},
+				},
+			}, nil
+		},
+		GetPartitionsFunc: func(ctx context.Context) (*slurm.PartitionInfoResponse, error) {
+			partitionCalls++
+			return &slurm.PartitionInfoResponse{
+				Partitions: []slurm.Partition{
+					{Name: "compute", State: "UP", QOS: "normal"},
+					{Name: "maint", State: "DRAIN"},
+				},
+			}, nil
+		},
+	}
+
+	tests := []struct {
+		name      string
+		job       config.JobConfig
+		want      []string
+		wantCalls int
+	}{
+		{
+			name:      "partitions are not fetched by default",
+			job:       config.JobConfig{Name: "node", Port: 9100},
+			want:      []string{"compute", "maint", "maint"},
+			wantCalls: 0,
+		},
+		{
+			name:      "annotate partitions",
+			job:       config.JobConfig{Name: "node", Port: 9100, AnnotatePartitions: true},
+			want:      []string{"compute", "maint", "maint"},
+			wantCalls: 1,
+		},
+		{
+			name:      "only up partitions",
+			job:       config.JobConfig{Name: "node", Port: 9100, OnlyUpPartitions: true},
+			want:      []string{"compute"},
+			wantCalls: 1,
+		},
+		{
+			name: "only up partitions with deduplication and partitionless nodes",
+			job: config.JobConfig{
+				Name: "node", Port: 9100, OnlyUpPartitions: true, DedupeNodes: true,
+				IncludePartitionless: true, PartitionlessPlaceholder: "none",
+			},
+			want:      []string{"compute", "none"},
+			wantCalls: 1,
+		},
+		{
+			name:      "only up partitions of slurm jobs",
+			job:       config.JobConfig{Name: "node", Kind: config.JobKindSlurmJob, Port: 8000, OnlyUpPartitions: true},
+			want:      []string{"compute"},
+			wantCalls: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			partitionCalls = 0
+			cfg := &config.Config{UpdateInterval: "5m", Jobs: []config.JobConfig{tc.job}}
+			service, err := NewService(mockClient, cfg, logger)
+			if err != nil {
+				t.Fatalf("Failed to create service: %v", err)
+			}
+			if err := service.updateTargets(context.Background()); err != nil {
+				t.Fatalf("updateTargets() error = %v", err)
+			}
+			if partitionCalls != tc.wantCalls {
+				t.Errorf("GetPartitions was called %d times, want %d", partitionCalls, tc.wantCalls)
+			}
+
+			targets, _ := service.GetTargets("node")
+			var got []string
+			for _, target := range targets {
+				got = append(got, target.Labels["__meta_slurm_partition"])
+
+				_, annotated := target.Labels["__meta_slurm_partition_state"]
+				if annotated != tc.job.AnnotatePartitions {
+					t.Errorf("Target %v has partition state label %v, want %v", target.Targets, annotated, tc.job.AnnotatePartitions)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Got partitions %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	// A failed partition fetch fails the refresh
+	partitionsErr := errors.New("connection refused")
+	mockClient.GetPartitionsFunc = func(ctx context.Context) (*slurm.PartitionInfoResponse, error) {
+		return nil, partitionsErr
+	}
+	cfg := &config.Config{UpdateInterval: "5m", Jobs: []config.JobConfig{{Name: "node", Port: 9100, OnlyUpPartitions: true}}}
+	service, err := NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := service.updateTargets(context.Background()); !errors.Is(err, partitionsErr) {
+		t.Errorf("updateTargets() error = %v, want %v", err, partitionsErr)
+	}
+}
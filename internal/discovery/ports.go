@@ -0,0 +1,80 @@
+package discovery
+
+import (
+	"fmt"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/config"
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+// portOverride is the compiled form of a config.PortOverride
+type portOverride struct {
+	selector *nodeSelector
+	ports    []config.PortConfig
+}
+
+// newPortOverrides compiles the port overrides of a job
+func newPortOverrides(job config.JobConfig) ([]portOverride, error) {
+	// Jobs reading their ports from Slurm job metadata may omit the port
+	if job.Metadata == nil || hasConfiguredPorts(job) {
+		if err := validatePorts(job.TargetPorts()); err != nil {
+			return nil, err
+		}
+	}
+
+	overrides := make([]portOverride, 0, len(job.PortOverrides))
+	for i, override := range job.PortOverrides {
+		if len(override.Ports) == 0 {
+			return nil, fmt.Errorf("port override %d has no ports", i)
+		}
+		if err := validatePorts(override.Ports); err != nil {
+			return nil, fmt.Errorf("port override %d: %w", i, err)
+		}
+		selector, err := newNodeSelector(override.NodeSelector)
+		if err != nil {
+			return nil, fmt.Errorf("port override %d: %w", i, err)
+		}
+		overrides = append(overrides, portOverride{selector: selector, ports: override.Ports})
+	}
+	return overrides, nil
+}
+
+// hasConfiguredPorts reports whether a job sets port or ports
+func hasConfiguredPorts(job config.JobConfig) bool {
+	return job.Port != 0 || len(job.Ports) > 0
+}
+
+// validatePorts checks that ports are in range and unique, and that the
+// names of named ports are unique. Ports may be left unnamed.
+func validatePorts(ports []config.PortConfig) error {
+	numbers := make(map[int]bool, len(ports))
+	names := make(map[string]bool, len(ports))
+	for _, port := range ports {
+		if port.Port < 1 || port.Port > 65535 {
+			return fmt.Errorf("invalid port %d", port.Port)
+		}
+		if numbers[port.Port] {
+			return fmt.Errorf("duplicate port %d", port.Port)
+		}
+		numbers[port.Port] = true
+		if port.Name == "" {
+			continue
+		}
+		if names[port.Name] {
+			return fmt.Errorf("duplicate port name %q", port.Name)
+		}
+		names[port.Name] = true
+	}
+	return nil
+}
+
+// targetPorts returns the ports to scrape on a node in the given partition:
+// those of the first matching override, or else the ports of the job
+func targetPorts(job config.JobConfig, overrides []portOverride, node slurm.Node, partition string) []config.PortConfig {
+	for _, override := range overrides {
+		if override.selector.matches(node) && len(override.selector.selectPartitions([]string{partition})) > 0 {
+			return override.ports
+		}
+	}
+	return job.TargetPorts()
+}
@@ -0,0 +1,133 @@
+package discovery
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/yuuki/prometheus-slurm-sd/internal/config"
+	"github.com/yuuki/prometheus-slurm-sd/internal/slurm"
+)
+
+func TestService_updateTargets_Ports(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	cfg := &config.Config{
+		UpdateInterval: "5m",
+		Jobs: []config.JobConfig{
+			{
+				Name: "dcgm",
+				Ports: []config.PortConfig{
+					{Name: "socket0", Port: 9400},
+				},
+				PortOverrides: []config.PortOverride{
+					{
+						NodeSelector: config.NodeSelector{Features: []string{"dual_socket"}},
+						Ports: []config.PortConfig{
+							{Name: "socket0", Port: 9400},
+							{Name: "socket1", Port: 9401},
+						},
+					},
+					{
+						NodeSelector: config.NodeSelector{Partitions: []string{"legacy"}},
+						Ports:        []config.PortConfig{{Name: "socket0", Port: 19400}},
+					},
+				},
+			},
+		},
+	}
+	mockClient := &MockSlurmClient{
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			return &slurm.NodeInfoResponse{
+				Nodes: []slurm.Node{
+					{Name: "gpu001", Address: "10.0.1.1", State: []string{"IDLE"}, Partitions: []string{"gpu"}, Features: []string{"dual_socket"}},
+					{Name: "gpu002", Address: "10.0.1.2", State: []string{"IDLE"}, Partitions: []string{"gpu", "legacy"}},
+				},
+			}, nil
+		},
+	}
+
+	service, err := NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := service.updateTargets(context.Background()); err != nil {
+		t.Fatalf("updateTargets() error = %v", err)
+	}
+
+	targets, _ := service.GetTargets("dcgm")
+	var got []string
+	for _, target := range targets {
+		got = append(got, target.Targets[0]+" "+target.Labels["__meta_slurm_partition"]+" "+target.Labels["__meta_slurm_port_name"])
+	}
+	want := []string{
+		"10.0.1.1:9400 gpu socket0",
+		"10.0.1.1:9401 gpu socket1",
+		"10.0.1.2:9400 gpu socket0",
+		"10.0.1.2:19400 legacy socket0",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Got targets %v, want %v", got, want)
+	}
+}
+
+func TestNewService_InvalidPorts(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	tests := []struct {
+		name string
+		job  config.JobConfig
+	}{
+		{
+			name: "port out of range",
+			job:  config.JobConfig{Name: "node", Ports: []config.PortConfig{{Name: "a", Port: 70000}}},
+		},
+		{
+			name: "duplicate port name",
+			job:  config.JobConfig{Name: "node", Ports: []config.PortConfig{{Name: "a", Port: 9100}, {Name: "a", Port: 9101}}},
+		},
+		{
+			name: "duplicate port number",
+			job:  config.JobConfig{Name: "node", Ports: []config.PortConfig{{Name: "a", Port: 9100}, {Name: "b", Port: 9100}}},
+		},
+		{
+			name: "override without ports",
+			job: config.JobConfig{Name: "node", Port: 9100, PortOverrides: []config.PortOverride{
+				{NodeSelector: config.NodeSelector{Partitions: []string{"gpu"}}},
+			}},
+		},
+		{
+			name: "override with invalid selector",
+			job: config.JobConfig{Name: "node", Port: 9100, PortOverrides: []config.PortOverride{
+				{NodeSelector: config.NodeSelector{Nodes: "gpu[1-"}, Ports: []config.PortConfig{{Port: 9101}}},
+			}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{
+				UpdateInterval: "5m",
+				Jobs:           []config.JobConfig{tc.job},
+			}
+			if _, err := NewService(&MockSlurmClient{}, cfg, logger); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidatePorts_Unnamed(t *testing.T) {
+	if err := validatePorts([]config.PortConfig{{Port: 9100}, {Port: 9101}}); err != nil {
+		t.Errorf("validatePorts() error = %v, want nil for distinct unnamed ports", err)
+	}
+	if err := validatePorts([]config.PortConfig{{Port: 9100}, {Name: "exporter", Port: 9101}}); err != nil {
+		t.Errorf("validatePorts() error = %v, want nil for mixed named and unnamed ports", err)
+	}
+}
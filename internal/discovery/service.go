@@ -3,9 +3,12 @@ package discovery
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 
@@ -16,6 +19,23 @@ import (
 // SlurmClient defines the interface for Slurm client interactions
 type SlurmClient interface {
 	GetNodes(ctx context.Context) (*slurm.NodeInfoResponse, error)
+	GetNodesSince(ctx context.Context, updateTime int64) (*slurm.NodeInfoResponse, error)
+	GetJobs(ctx context.Context) (*slurm.JobInfoResponse, error)
+	GetPartitions(ctx context.Context) (*slurm.PartitionInfoResponse, error)
+}
+
+// apiVersionReporter is implemented by Slurm clients that can report the REST API version in use
+type apiVersionReporter interface {
+	APIVersion() string
+}
+
+// Status represents the state of the service discovery service
+type Status struct {
+	SlurmAPIVersion string     `json:"slurm_api_version,omitempty"`
+	LastUpdate      *time.Time `json:"last_update,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	Jobs            int        `json:"jobs"`
+	Targets         int        `json:"targets"`
 }
 
 // PrometheusTarget represents a Prometheus service discovery target
@@ -24,15 +44,45 @@ type PrometheusTarget struct {
 	Labels  map[string]string `json:"labels"`
 }
 
+// errTargetDropRejected is returned when the drop guard keeps the previous
+// targets. Slurm did respond, so it is not a failed refresh.
+var errTargetDropRejected = errors.New("target update rejected by the drop guard")
+
 // Service is the Prometheus service discovery service
 type Service struct {
 	slurmClient SlurmClient
 	config      *config.Config
 	logger      *slog.Logger
 
-	targetsCache      map[string][]PrometheusTarget
-	targetsCacheMutex sync.RWMutex
-	updateInterval    time.Duration
+	targetsCache       map[string][]PrometheusTarget
+	targetsCacheMutex  sync.RWMutex
+	updateInterval     time.Duration
+	retryInterval      time.Duration
+	fullResyncInterval time.Duration
+
+	// Compiled jobs, in the order of config.Jobs
+	jobs []*compiledJob
+
+	// Consecutive failed refreshes, only accessed by Start
+	consecutiveFailures int
+
+	// Node cache used for incremental updates, only accessed by updateTargets
+	nodes          map[string]slurm.Node
+	nodesUpdatedAt int64
+	lastFullSync   time.Time
+
+	// Consecutive refreshes whose target count dropped beyond the limit
+	pendingDrops int
+
+	// Slurm job IDs whose malformed metadata was already logged, per job
+	// name, only accessed by updateTargets
+	metadataWarned map[string]map[int64]bool
+
+	lastUpdate  time.Time
+	lastError   string
+	statusMutex sync.RWMutex
+
+	metrics metrics
 }
 
 // NewService creates a new service discovery service
@@ -42,81 +92,165 @@ func NewService(slurmClient SlurmClient, cfg *config.Config, logger *slog.Logger
 		return nil, fmt.Errorf("invalid update interval: %w", err)
 	}
 
+	var retryInterval time.Duration
+	if cfg.RetryInterval != "" {
+		retryInterval, err = time.ParseDuration(cfg.RetryInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry interval: %w", err)
+		}
+	}
+
+	var fullResyncInterval time.Duration
+	if cfg.IncrementalUpdates && cfg.FullResyncInterval != "" {
+		fullResyncInterval, err = time.ParseDuration(cfg.FullResyncInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid full resync interval: %w", err)
+		}
+	}
+
+	jobs := make([]*compiledJob, 0, len(cfg.Jobs))
+	for _, jobCfg := range cfg.Jobs {
+		j, err := compileJob(jobCfg, cfg.AddressFamily)
+		if err != nil {
+			return nil, fmt.Errorf("invalid job %q: %w", jobCfg.Name, err)
+		}
+		jobs = append(jobs, j)
+	}
+
 	return &Service{
-		slurmClient:    slurmClient,
-		config:         cfg,
-		logger:         logger,
-		targetsCache:   make(map[string][]PrometheusTarget),
-		updateInterval: updateInterval,
+		slurmClient:        slurmClient,
+		config:             cfg,
+		logger:             logger,
+		targetsCache:       make(map[string][]PrometheusTarget),
+		updateInterval:     updateInterval,
+		retryInterval:      retryInterval,
+		fullResyncInterval: fullResyncInterval,
+		nodes:              make(map[string]slurm.Node),
+		metadataWarned:     make(map[string]map[int64]bool),
+		jobs:               jobs,
 	}, nil
 }
 
 // Start initiates the service discovery service
 func (s *Service) Start(ctx context.Context) error {
 	// Initial fetch
-	if err := s.updateTargets(ctx); err != nil {
+	err := s.refresh(ctx)
+	if err != nil && !errors.Is(err, errTargetDropRejected) {
 		s.logger.Error("Failed to update targets on startup", "error", err)
 	}
 
 	// Periodic update process
-	ticker := time.NewTicker(s.updateInterval)
-	defer ticker.Stop()
+	timer := time.NewTimer(s.nextDelay(err))
+	defer timer.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			if err := s.updateTargets(ctx); err != nil {
+		case <-timer.C:
+			err := s.refresh(ctx)
+			if err != nil && !errors.Is(err, errTargetDropRejected) {
 				s.logger.Error("Failed to update targets", "error", err)
 			}
+			timer.Reset(s.nextDelay(err))
 		case <-ctx.Done():
 			return ctx.Err()
 		}
 	}
 }
 
+// nextDelay returns the delay until the next refresh. After a failure the
+// refresh is retried sooner, starting at the retry interval and doubling on
+// each consecutive failure up to the update interval. Updates rejected by the
+// drop guard wait for the update interval, so that its confirmations span
+// several regular refreshes.
+func (s *Service) nextDelay(err error) time.Duration {
+	if err == nil || errors.Is(err, errTargetDropRejected) {
+		s.consecutiveFailures = 0
+		return s.updateInterval
+	}
+
+	s.consecutiveFailures++
+	if s.retryInterval <= 0 || s.retryInterval >= s.updateInterval {
+		return s.updateInterval
+	}
+
+	delay := s.retryInterval
+	for i := 1; i < s.consecutiveFailures && delay < s.updateInterval; i++ {
+		delay *= 2
+	}
+	if delay > s.updateInterval {
+		delay = s.updateInterval
+	}
+	s.logger.Info("Scheduling retry after failed refresh", "delay", delay, "failures", s.consecutiveFailures)
+	return delay
+}
+
+// refresh updates the target cache and records the outcome for the status endpoint
+func (s *Service) refresh(ctx context.Context) error {
+	err := s.updateTargets(ctx)
+
+	s.metrics.refreshes.Add(1)
+	if errors.Is(err, errTargetDropRejected) {
+		// The previous targets are kept and the guard already logged the drop
+		return err
+	}
+	s.statusMutex.Lock()
+	defer s.statusMutex.Unlock()
+	if err != nil {
+		s.metrics.refreshFailures.Add(1)
+		s.lastError = err.Error()
+		return err
+	}
+	s.lastUpdate = time.Now()
+	s.lastError = ""
+	return nil
+}
+
 // updateTargets fetches node information from Slurm and updates the target cache
 func (s *Service) updateTargets(ctx context.Context) error {
-	// Call Slurm API to get node information
-	nodeInfo, err := s.slurmClient.GetNodes(ctx)
+	nodes, err := s.fetchNodes(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get nodes from Slurm: %w", err)
+		return err
 	}
 
-	// Generate targets for each job
-	jobTargets := make(map[string][]PrometheusTarget)
-	for _, job := range s.config.Jobs {
-		var targets []PrometheusTarget
+	// Nodes without a partition produce no targets unless a job includes them
+	var partitionless uint64
+	for _, node := range nodes {
+		if len(node.Partitions) == 0 {
+			partitionless++
+		}
+	}
+	s.metrics.partitionlessNodes.Store(partitionless)
 
-		// Process each node
-		for _, node := range nodeInfo.Nodes {
-			// Get node state
-			nodeState := "unknown"
-			if len(node.State) > 0 {
-				nodeState = node.State[0]
-			}
+	var slurmJobs []runningJob
+	if s.needsJobs() {
+		if slurmJobs, err = s.fetchJobs(ctx); err != nil {
+			return err
+		}
+	}
+	nodeJobs := jobsByNode(slurmJobs)
 
-			// Get node address
-			nodeAddress := node.Address
-			if nodeAddress == "" {
-				nodeAddress = node.Hostname
-			}
+	var partitions map[string]slurm.Partition
+	if s.needsPartitions() {
+		if partitions, err = s.fetchPartitions(ctx); err != nil {
+			return err
+		}
+	}
 
-			// Create target for each partition
-			for _, partition := range node.Partitions {
-				target := PrometheusTarget{
-					Targets: []string{fmt.Sprintf("%s:%d", nodeAddress, job.Port)},
-					Labels: map[string]string{
-						"__meta_slurm_partition": partition,
-						"__meta_slurm_job":       job.Name,
-						"__meta_slurm_state":     nodeState,
-						"__meta_slurm_node":      node.Name,
-					},
-				}
-				targets = append(targets, target)
-			}
+	// Generate targets for each job
+	jobTargets := make(map[string][]PrometheusTarget)
+	for _, job := range s.jobs {
+		var targets []PrometheusTarget
+		switch job.Kind {
+		case config.JobKindSlurmJob:
+			targets = s.slurmJobTargets(job, slurmJobs, nodes, partitions)
+		default:
+			targets = s.nodeTargets(job, nodes, nodeJobs, partitions)
 		}
+		jobTargets[job.Name] = groupTargets(targets, job.GroupBy)
+	}
 
-		jobTargets[job.Name] = targets
+	if err := s.guardTargetDrop(jobTargets); err != nil {
+		return err
 	}
 
 	// Update cache
@@ -128,6 +262,200 @@ func (s *Service) updateTargets(ctx context.Context) error {
 	return nil
 }
 
+// nodeTargets returns the targets of a node job. nodeJobs holds the running
+// Slurm jobs of each node and partitions the Slurm partitions by name, used
+// by jobs that annotate their targets.
+func (s *Service) nodeTargets(job *compiledJob, nodes []slurm.Node, nodeJobs map[string][]slurm.Job, partitions map[string]slurm.Partition) []PrometheusTarget {
+	var targets []PrometheusTarget
+	includeStates, excludeStates := s.config.StateFilter(job.JobConfig)
+
+	for _, node := range nodes {
+		if !matchesStates(node, includeStates, excludeStates) || !job.selector.matches(node) {
+			continue
+		}
+
+		attributes := nodeLabels(node)
+		maps.Copy(attributes, stateLabels(node.State))
+		if job.AnnotateJobs {
+			maps.Copy(attributes, nodeJobLabels(nodeJobs[node.Name]))
+		}
+
+		nodePartitions := node.Partitions
+		if len(nodePartitions) == 0 && job.IncludePartitionless {
+			nodePartitions = []string{job.PartitionlessPlaceholder}
+		}
+		nodePartitions = job.upPartitions(job.selector.selectPartitions(nodePartitions), partitions)
+
+		// With deduplication the node is emitted once, under its first
+		// partition, and lists all of its partitions in labels
+		if job.DedupeNodes && len(nodePartitions) > 0 {
+			maps.Copy(attributes, partitionLabels(nodePartitions))
+			nodePartitions = nodePartitions[:1]
+		}
+
+		// Create target for each partition
+		for _, partition := range nodePartitions {
+			ports := targetPorts(job.JobConfig, job.portOverrides, node, partition)
+			partitionAttributes := job.withPartitionAttributes(attributes, partitions, partition)
+			targets = s.appendTargets(targets, job, node, slurm.Job{}, partition, ports, partitionAttributes)
+		}
+	}
+	return targets
+}
+
+// appendTargets appends a target for every port on a node in the given
+// partition. Labels are copied and completed with the common labels.
+func (s *Service) appendTargets(targets []PrometheusTarget, job *compiledJob, node slurm.Node, slurmJob slurm.Job, partition string, ports []config.PortConfig, attributes map[string]string) []PrometheusTarget {
+	for _, port := range ports {
+		labels := maps.Clone(attributes)
+		labels["__meta_slurm_partition"] = partition
+		labels["__meta_slurm_job"] = job.Name
+		labels["__meta_slurm_node"] = node.Name
+		if port.Name != "" {
+			labels["__meta_slurm_port_name"] = port.Name
+		}
+
+		host, err := job.targetHost(node, slurmJob, labels)
+		if err != nil {
+			s.logger.Warn("Skipping target whose address could not be rendered", "job", job.Name, "node", node.Name, "error", err)
+			continue
+		}
+
+		targets = append(targets, PrometheusTarget{
+			Targets: []string{joinHostPort(host, port.Port)},
+			Labels:  labels,
+		})
+	}
+	return targets
+}
+
+// guardTargetDrop rejects a new target set that shrank by more than
+// max_target_drop_percent compared to the cache, until the drop has been
+// seen on target_drop_confirmations consecutive refreshes
+func (s *Service) guardTargetDrop(jobTargets map[string][]PrometheusTarget) error {
+	if s.config.MaxTargetDropPercent <= 0 {
+		return nil
+	}
+
+	s.targetsCacheMutex.RLock()
+	previous := countTargets(s.targetsCache)
+	s.targetsCacheMutex.RUnlock()
+	current := countTargets(jobTargets)
+
+	if previous == 0 || current >= previous {
+		s.pendingDrops = 0
+		return nil
+	}
+	dropPercent := float64(previous-current) / float64(previous) * 100
+	if dropPercent <= s.config.MaxTargetDropPercent {
+		s.pendingDrops = 0
+		return nil
+	}
+
+	s.pendingDrops++
+	if s.pendingDrops >= s.config.TargetDropConfirmations {
+		s.logger.Warn("Accepting target drop confirmed by consecutive refreshes",
+			"previous", previous, "current", current, "drop_percent", dropPercent, "refreshes", s.pendingDrops)
+		s.pendingDrops = 0
+		return nil
+	}
+
+	s.metrics.targetDropsRejected.Add(1)
+	s.logger.Warn("Rejecting target update with excessive drop, keeping previous targets",
+		"previous", previous, "current", current, "drop_percent", dropPercent,
+		"confirmations", s.pendingDrops, "required", s.config.TargetDropConfirmations)
+	return fmt.Errorf("%w: target count dropped by %.1f%% (%d -> %d), exceeding the limit of %.1f%%",
+		errTargetDropRejected, dropPercent, previous, current, s.config.MaxTargetDropPercent)
+}
+
+// countTargets returns the total number of target addresses across all jobs
+func countTargets(jobTargets map[string][]PrometheusTarget) int {
+	count := 0
+	for _, targets := range jobTargets {
+		for _, target := range targets {
+			count += len(target.Targets)
+		}
+	}
+	return count
+}
+
+// fetchNodes returns the current node list. With incremental updates enabled,
+// only nodes changed since the last response are requested and merged into
+// the node cache, and a full fetch is done every full resync interval so that
+// removed nodes eventually disappear.
+func (s *Service) fetchNodes(ctx context.Context) ([]slurm.Node, error) {
+	now := time.Now()
+	incremental := s.config.IncrementalUpdates && s.nodesUpdatedAt > 0 &&
+		(s.fullResyncInterval <= 0 || now.Sub(s.lastFullSync) < s.fullResyncInterval)
+
+	var nodeInfo *slurm.NodeInfoResponse
+	var err error
+	if incremental {
+		nodeInfo, err = s.slurmClient.GetNodesSince(ctx, s.nodesUpdatedAt)
+	} else {
+		nodeInfo, err = s.slurmClient.GetNodes(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nodes from Slurm: %w", err)
+	}
+	if err := s.checkResponse(nodeInfo.Errors, nodeInfo.Warnings); err != nil {
+		return nil, fmt.Errorf("failed to get nodes from Slurm: %w", err)
+	}
+
+	if incremental {
+		for _, node := range nodeInfo.Nodes {
+			s.nodes[node.Name] = node
+		}
+		s.logger.Debug("Merged incremental node update", "changed", len(nodeInfo.Nodes), "total", len(s.nodes))
+	} else {
+		s.nodes = make(map[string]slurm.Node, len(nodeInfo.Nodes))
+		for _, node := range nodeInfo.Nodes {
+			s.nodes[node.Name] = node
+		}
+		s.lastFullSync = now
+	}
+	if nodeInfo.LastUpdate != nil && nodeInfo.LastUpdate.Set && nodeInfo.LastUpdate.Number > s.nodesUpdatedAt {
+		s.nodesUpdatedAt = nodeInfo.LastUpdate.Number
+	}
+
+	if !s.config.IncrementalUpdates {
+		// Preserve the order returned by Slurm
+		return nodeInfo.Nodes, nil
+	}
+
+	nodes := make([]slurm.Node, 0, len(s.nodes))
+	for _, node := range s.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	return nodes, nil
+}
+
+// checkResponse logs the warnings of a Slurm REST API response and returns an
+// error when the response carries errors, so that a 200 response with errors
+// does not replace the cache with a possibly incomplete node list
+func (s *Service) checkResponse(errs []slurm.Error, warnings []slurm.Warning) error {
+	s.metrics.slurmAPIWarnings.Add(uint64(len(warnings)))
+	for _, warning := range warnings {
+		s.logger.Warn("Slurm API returned a warning", "description", warning.Description, "source", warning.Source)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	s.metrics.slurmAPIErrors.Add(uint64(len(errs)))
+	for _, e := range errs {
+		s.logger.Error("Slurm API returned an error",
+			"description", e.Description, "error", e.Error, "error_number", e.ErrorNumber, "source", e.Source)
+	}
+	first := errs[0]
+	message := first.Description
+	if message == "" {
+		message = first.Error
+	}
+	return fmt.Errorf("slurm API returned %d error(s): %s", len(errs), message)
+}
+
 // GetTargets returns targets for the specified job
 func (s *Service) GetTargets(jobName string) ([]PrometheusTarget, bool) {
 	s.targetsCacheMutex.RLock()
@@ -150,6 +478,41 @@ func (s *Service) GetAllTargets() []PrometheusTarget {
 	return allTargets
 }
 
+// GetStatus returns the current status of the service
+func (s *Service) GetStatus() Status {
+	var status Status
+	if reporter, ok := s.slurmClient.(apiVersionReporter); ok {
+		status.SlurmAPIVersion = reporter.APIVersion()
+	}
+
+	s.statusMutex.RLock()
+	if !s.lastUpdate.IsZero() {
+		lastUpdate := s.lastUpdate
+		status.LastUpdate = &lastUpdate
+	}
+	status.LastError = s.lastError
+	s.statusMutex.RUnlock()
+
+	s.targetsCacheMutex.RLock()
+	status.Jobs = len(s.targetsCache)
+	status.Targets = countTargets(s.targetsCache)
+	s.targetsCacheMutex.RUnlock()
+
+	return status
+}
+
+// StatusHandler is the handler for the service status endpoint
+func (s *Service) StatusHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(s.GetStatus()); err != nil {
+			s.logger.Error("Failed to encode status", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
 // HTTPHandler is the handler for Prometheus HTTP Service Discovery requests
 func (s *Service) HTTPHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -3,10 +3,13 @@ package discovery
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -16,7 +19,43 @@ import (
 
 // MockSlurmClient is a mock implementation of the Slurm client for testing
 type MockSlurmClient struct {
-	GetNodesFunc func(ctx context.Context) (*slurm.NodeInfoResponse, error)
+	GetNodesFunc      func(ctx context.Context) (*slurm.NodeInfoResponse, error)
+	GetNodesSinceFunc func(ctx context.Context, updateTime int64) (*slurm.NodeInfoResponse, error)
+	GetJobsFunc       func(ctx context.Context) (*slurm.JobInfoResponse, error)
+	GetPartitionsFunc func(ctx context.Context) (*slurm.PartitionInfoResponse, error)
+	Version           string
+}
+
+// GetPartitions is the mock implementation of GetPartitions.
+// It returns no partitions when GetPartitionsFunc is not set.
+func (m *MockSlurmClient) GetPartitions(ctx context.Context) (*slurm.PartitionInfoResponse, error) {
+	if m.GetPartitionsFunc == nil {
+		return &slurm.PartitionInfoResponse{}, nil
+	}
+	return m.GetPartitionsFunc(ctx)
+}
+
+// GetJobs is the mock implementation of GetJobs.
+// It returns no jobs when GetJobsFunc is not set.
+func (m *MockSlurmClient) GetJobs(ctx context.Context) (*slurm.JobInfoResponse, error) {
+	if m.GetJobsFunc == nil {
+		return &slurm.JobInfoResponse{}, nil
+	}
+	return m.GetJobsFunc(ctx)
+}
+
+// GetNodesSince is the mock implementation of GetNodesSince.
+// It falls back to GetNodesFunc when GetNodesSinceFunc is not set.
+func (m *MockSlurmClient) GetNodesSince(ctx context.Context, updateTime int64) (*slurm.NodeInfoResponse, error) {
+	if m.GetNodesSinceFunc == nil {
+		return m.GetNodesFunc(ctx)
+	}
+	return m.GetNodesSinceFunc(ctx, updateTime)
+}
+
+// APIVersion is the mock implementation of APIVersion
+func (m *MockSlurmClient) APIVersion() string {
+	return m.Version
 }
 
 // GetNodes is the mock implementation of GetNodes
@@ -432,3 +471,377 @@ func TestService_Start(t *testing.T) {
 		t.Errorf("Expected multiple calls to GetNodes, got %d", callCount)
 	}
 }
+
+func TestService_StatusHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	cfg := &config.Config{
+		UpdateInterval: "5m",
+		Jobs: []config.JobConfig{
+			{Name: "node", Port: 9100},
+		},
+	}
+
+	fail := false
+	mockClient := &MockSlurmClient{
+		Version: "v0.0.41",
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			if fail {
+				return nil, errors.New("connection refused")
+			}
+			return &slurm.NodeInfoResponse{
+				Nodes: []slurm.Node{
+					{Name: "node1", Address: "10.0.0.1", State: []string{"IDLE"}, Partitions: []string{"compute"}},
+				},
+			}, nil
+		},
+	}
+
+	service, err := NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	getStatus := func() Status {
+		rr := httptest.NewRecorder()
+		service.StatusHandler()(rr, httptest.NewRequest("GET", "/status", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		var status Status
+		if err := json.NewDecoder(rr.Body).Decode(&status); err != nil {
+			t.Fatalf("Failed to decode status: %v", err)
+		}
+		return status
+	}
+
+	if err := service.refresh(context.Background()); err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+	status := getStatus()
+	if status.SlurmAPIVersion != "v0.0.41" || status.LastUpdate == nil || status.LastError != "" || status.Targets != 1 {
+		t.Errorf("Unexpected status after successful refresh: %+v", status)
+	}
+
+	fail = true
+	if err := service.refresh(context.Background()); err == nil {
+		t.Fatal("Expected refresh error, got nil")
+	}
+	status = getStatus()
+	if status.LastError == "" || status.LastUpdate == nil || status.Targets != 1 {
+		t.Errorf("Unexpected status after failed refresh: %+v", status)
+	}
+}
+
+func TestService_IncrementalUpdates(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	cfg := &config.Config{
+		UpdateInterval:     "5m",
+		IncrementalUpdates: true,
+		FullResyncInterval: "1h",
+		Jobs: []config.JobConfig{
+			{Name: "node", Port: 9100},
+		},
+	}
+
+	fullCalls := 0
+	var sinceCalls []int64
+	mockClient := &MockSlurmClient{
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			fullCalls++
+			return &slurm.NodeInfoResponse{
+				Nodes: []slurm.Node{
+					{Name: "node1", Address: "10.0.0.1", State: []string{"IDLE"}, Partitions: []string{"compute"}},
+					{Name: "node2", Address: "10.0.0.2", State: []string{"IDLE"}, Partitions: []string{"compute"}},
+				},
+				LastUpdate: &slurm.TimeValue{Number: 1000, Set: true},
+			}, nil
+		},
+		GetNodesSinceFunc: func(ctx context.Context, updateTime int64) (*slurm.NodeInfoResponse, error) {
+			sinceCalls = append(sinceCalls, updateTime)
+			return &slurm.NodeInfoResponse{
+				Nodes: []slurm.Node{
+					{Name: "node2", Address: "10.0.0.2", State: []string{"DOWN"}, Partitions: []string{"compute"}},
+				},
+				LastUpdate: &slurm.TimeValue{Number: 2000, Set: true},
+			}, nil
+		},
+	}
+
+	service, err := NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	// First refresh is a full fetch
+	if err := service.updateTargets(context.Background()); err != nil {
+		t.Fatalf("Failed to update targets: %v", err)
+	}
+	// Second refresh only requests nodes changed since the first response
+	if err := service.updateTargets(context.Background()); err != nil {
+		t.Fatalf("Failed to update targets: %v", err)
+	}
+
+	if fullCalls != 1 || len(sinceCalls) != 1 || sinceCalls[0] != 1000 {
+		t.Fatalf("Unexpected calls: full=%d since=%v", fullCalls, sinceCalls)
+	}
+
+	targets, _ := service.GetTargets("node")
+	states := make(map[string]string)
+	for _, target := range targets {
+		states[target.Labels["__meta_slurm_node"]] = target.Labels["__meta_slurm_state"]
+	}
+	if len(states) != 2 || states["node1"] != "IDLE" || states["node2"] != "DOWN" {
+		t.Errorf("Unexpected merged node states: %v", states)
+	}
+
+	// A full resync happens once the resync interval has elapsed
+	service.lastFullSync = time.Now().Add(-2 * time.Hour)
+	if err := service.updateTargets(context.Background()); err != nil {
+		t.Fatalf("Failed to update targets: %v", err)
+	}
+	if fullCalls != 2 {
+		t.Errorf("Expected a full resync, got %d full fetches", fullCalls)
+	}
+}
+
+func TestService_TargetDropGuard(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	cfg := &config.Config{
+		UpdateInterval:          "5m",
+		MaxTargetDropPercent:    50,
+		TargetDropConfirmations: 2,
+		Jobs: []config.JobConfig{
+			{Name: "node", Port: 9100},
+		},
+	}
+
+	makeNodes := func(n int) []slurm.Node {
+		var nodes []slurm.Node
+		for i := 0; i < n; i++ {
+			nodes = append(nodes, slurm.Node{
+				Name:       fmt.Sprintf("node%d", i),
+				Address:    fmt.Sprintf("10.0.0.%d", i),
+				State:      []string{"IDLE"},
+				Partitions: []string{"compute"},
+			})
+		}
+		return nodes
+	}
+
+	nodeCount := 10
+	mockClient := &MockSlurmClient{
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			return &slurm.NodeInfoResponse{Nodes: makeNodes(nodeCount)}, nil
+		},
+	}
+
+	service, err := NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	targetCount := func() int {
+		targets, _ := service.GetTargets("node")
+		return len(targets)
+	}
+
+	steps := []struct {
+		nodes       int
+		wantErr     bool
+		wantTargets int
+	}{
+		{nodes: 10, wantErr: false, wantTargets: 10}, // initial fill
+		{nodes: 6, wantErr: false, wantTargets: 6},   // 40% drop is within the limit
+		{nodes: 0, wantErr: true, wantTargets: 6},    // 100% drop is rejected
+		{nodes: 6, wantErr: false, wantTargets: 6},   // recovery resets the confirmation count
+		{nodes: 2, wantErr: true, wantTargets: 6},    // rejected, first confirmation
+		{nodes: 2, wantErr: false, wantTargets: 2},   // accepted after two consecutive refreshes
+	}
+
+	for i, step := range steps {
+		nodeCount = step.nodes
+		err := service.updateTargets(context.Background())
+		if (err != nil) != step.wantErr {
+			t.Errorf("step %d: updateTargets() error = %v, wantErr %v", i, err, step.wantErr)
+		}
+		if got := targetCount(); got != step.wantTargets {
+			t.Errorf("step %d: got %d targets, want %d", i, got, step.wantTargets)
+		}
+	}
+
+	if got := service.metrics.targetDropsRejected.Load(); got != 2 {
+		t.Errorf("targetDropsRejected = %d, want 2", got)
+	}
+}
+
+func TestService_nextDelay(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	cfg := &config.Config{
+		UpdateInterval: "5m",
+		RetryInterval:  "30s",
+	}
+	service, err := NewService(&MockSlurmClient{}, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	failure := errors.New("connection refused")
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, w := range want {
+		if got := service.nextDelay(failure); got != w {
+			t.Errorf("failure %d: nextDelay() = %v, want %v", i+1, got, w)
+		}
+	}
+
+	// Success resets the backoff
+	if got := service.nextDelay(nil); got != 5*time.Minute {
+		t.Errorf("nextDelay(nil) = %v, want 5m", got)
+	}
+	if got := service.nextDelay(failure); got != 30*time.Second {
+		t.Errorf("nextDelay() after success = %v, want 30s", got)
+	}
+
+	// Updates rejected by the drop guard wait for the update interval, so
+	// that confirmations are not rushed by the retry schedule
+	rejected := fmt.Errorf("%w: target count dropped", errTargetDropRejected)
+	for i := 0; i < 3; i++ {
+		if got := service.nextDelay(rejected); got != 5*time.Minute {
+			t.Errorf("rejection %d: nextDelay() = %v, want 5m", i+1, got)
+		}
+	}
+	if got := service.nextDelay(failure); got != 30*time.Second {
+		t.Errorf("nextDelay() after rejection = %v, want 30s", got)
+	}
+}
+
+func TestService_refresh_TargetDropRejected(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	cfg := &config.Config{
+		UpdateInterval:          "5m",
+		MaxTargetDropPercent:    50,
+		TargetDropConfirmations: 3,
+		Jobs:                    []config.JobConfig{{Name: "node", Port: 9100}},
+	}
+	nodes := []slurm.Node{
+		{Name: "node1", Address: "10.0.0.1", State: []string{"IDLE"}, Partitions: []string{"compute"}},
+		{Name: "node2", Address: "10.0.0.2", State: []string{"IDLE"}, Partitions: []string{"compute"}},
+	}
+	mockClient := &MockSlurmClient{
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			return &slurm.NodeInfoResponse{Nodes: nodes}, nil
+		},
+	}
+
+	service, err := NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := service.refresh(context.Background()); err != nil {
+		t.Fatalf("refresh() error = %v", err)
+	}
+
+	nodes = nil
+	if err := service.refresh(context.Background()); !errors.Is(err, errTargetDropRejected) {
+		t.Fatalf("refresh() error = %v, want %v", err, errTargetDropRejected)
+	}
+	if got := service.metrics.refreshFailures.Load(); got != 0 {
+		t.Errorf("refreshFailures = %d, want 0", got)
+	}
+	if status := service.GetStatus(); status.LastError != "" || status.Targets != 2 {
+		t.Errorf("Unexpected status after a rejected update: %+v", status)
+	}
+}
+
+func TestService_Start_RetriesSooner(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	cfg := &config.Config{
+		UpdateInterval: "1h",
+		RetryInterval:  "10ms",
+		Jobs: []config.JobConfig{
+			{Name: "test", Port: 9100},
+		},
+	}
+
+	var calls atomic.Int32
+	mockClient := &MockSlurmClient{
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			if calls.Add(1) == 1 {
+				return nil, errors.New("connection refused")
+			}
+			return &slurm.NodeInfoResponse{}, nil
+		},
+	}
+
+	service, err := NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- service.Start(ctx)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	<-errCh
+
+	// The failed startup refresh is retried well before the update interval,
+	// and no further refresh happens once it succeeds
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected 2 calls to GetNodes, got %d", got)
+	}
+}
+
+func TestService_GetStatus_GroupedTargets(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	cfg := &config.Config{
+		UpdateInterval: "5m",
+		Jobs:           []config.JobConfig{{Name: "node", Port: 9100, GroupBy: config.GroupByPartition}},
+	}
+	mockClient := &MockSlurmClient{
+		GetNodesFunc: func(ctx context.Context) (*slurm.NodeInfoResponse, error) {
+			return &slurm.NodeInfoResponse{
+				Nodes: []slurm.Node{
+					{Name: "node1", Address: "10.0.0.1", State: []string{"IDLE"}, Partitions: []string{"compute"}},
+					{Name: "node2", Address: "10.0.0.2", State: []string{"IDLE"}, Partitions: []string{"compute"}},
+				},
+			}, nil
+		},
+	}
+
+	service, err := NewService(mockClient, cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := service.refresh(context.Background()); err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+
+	// Both addresses share one group but are counted separately
+	if status := service.GetStatus(); status.Targets != 2 {
+		t.Errorf("status.Targets = %d, want 2", status.Targets)
+	}
+}
@@ -2,22 +2,34 @@ package slurm
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+// unixSocketHost is the placeholder host used in request URLs when
+// slurmrestd is reached over a UNIX domain socket
+const unixSocketHost = "http://localhost"
+
 // Client is the Slurm REST API client
 type Client struct {
-	baseURL    string
-	apiVersion string
-	username   string
-	token      string
-	httpClient *http.Client
-	logger     *slog.Logger
+	baseURL     string
+	apiVersion  string
+	versionMu   sync.Mutex
+	negotiateMu sync.Mutex
+	username    string
+	token       string
+	tokenSource TokenSource
+	tlsConfig   *TLSConfig
+	retryPolicy RetryPolicy
+	httpClient  *http.Client
+	logger      *slog.Logger
 }
 
 // NodeInfoResponse represents the Slurm node information response
@@ -29,14 +41,35 @@ type NodeInfoResponse struct {
 	Warnings   []Warning  `json:"warnings,omitempty"`
 }
 
-// Node represents Slurm node information
+// Node represents Slurm node information normalized across REST API versions
 type Node struct {
-	Name       string   `json:"name"`
-	Address    string   `json:"address"`
-	Hostname   string   `json:"hostname"`
-	State      []string `json:"state"`
-	Partitions []string `json:"partitions"`
-	// Add other necessary fields
+	Name     string `json:"name"`
+	Address  string `json:"address"`
+	Hostname string `json:"hostname"`
+	// State holds the base state followed by any state flags, in upper case
+	State          []string `json:"state"`
+	Partitions     []string `json:"partitions"`
+	Features       []string `json:"features,omitempty"`
+	ActiveFeatures []string `json:"active_features,omitempty"`
+	Gres           string   `json:"gres,omitempty"`
+	GresUsed       string   `json:"gres_used,omitempty"`
+	CPUs           int64    `json:"cpus,omitempty"`
+	Sockets        int64    `json:"sockets,omitempty"`
+	Cores          int64    `json:"cores,omitempty"`
+	Threads        int64    `json:"threads,omitempty"`
+	RealMemory     int64    `json:"real_memory,omitempty"`
+	FreeMemory     int64    `json:"free_memory,omitempty"`
+	// BootTime is a UNIX timestamp, zero when unknown
+	BootTime        int64  `json:"boot_time,omitempty"`
+	Architecture    string `json:"architecture,omitempty"`
+	OperatingSystem string `json:"operating_system,omitempty"`
+	ClusterName     string `json:"cluster_name,omitempty"`
+	Reason          string `json:"reason,omitempty"`
+	Comment         string `json:"comment,omitempty"`
+	Extra           string `json:"extra,omitempty"`
+	InstanceType    string `json:"instance_type,omitempty"`
+	// SlurmdVersion is the version of the slurmd running on the node
+	SlurmdVersion string `json:"slurmd_version,omitempty"`
 }
 
 // TimeValue represents a Slurm timestamp value
@@ -96,53 +129,187 @@ type Warning struct {
 	Source      string `json:"source"`
 }
 
-// NewClient creates a new Slurm client
-func NewClient(baseURL, apiVersion, username, token string, logger *slog.Logger) *Client {
-	return &Client{
-		baseURL:    baseURL,
+// ClientOption configures optional Client behavior
+type ClientOption func(*Client) error
+
+// WithTLSConfig configures TLS and mutual TLS for connections to slurmrestd
+func WithTLSConfig(cfg TLSConfig) ClientOption {
+	return func(c *Client) error {
+		c.tlsConfig = &cfg
+		return nil
+	}
+}
+
+// NewClient creates a new Slurm client.
+// baseURL is either an HTTP(S) URL or a UNIX domain socket path in the
+// form of "unix:///path/to/slurmrestd.sock".
+func NewClient(baseURL, apiVersion, username, token string, logger *slog.Logger, opts ...ClientOption) (*Client, error) {
+	c := &Client{
 		apiVersion: apiVersion,
 		username:   username,
 		token:      token,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		logger: logger,
+		logger:     logger,
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if socketPath, ok := parseUnixSocketURL(baseURL); ok {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		}
+		baseURL = unixSocketHost
+	}
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+
+	var roundTripper http.RoundTripper = transport
+	if c.tlsConfig != nil {
+		tlsTransport, err := newTLSTransport(*c.tlsConfig, transport, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure TLS: %w", err)
+		}
+		roundTripper = tlsTransport
+	}
+
+	c.httpClient = &http.Client{
+		Transport: roundTripper,
+		Timeout:   30 * time.Second,
+	}
+
+	return c, nil
+}
+
+// parseUnixSocketURL extracts the socket path from a "unix://" or "unix:" endpoint
+func parseUnixSocketURL(endpoint string) (string, bool) {
+	switch {
+	case strings.HasPrefix(endpoint, "unix://"):
+		return strings.TrimPrefix(endpoint, "unix://"), true
+	case strings.HasPrefix(endpoint, "unix:"):
+		return strings.TrimPrefix(endpoint, "unix:"), true
+	default:
+		return "", false
 	}
 }
 
 // GetNodes retrieves Slurm node information
 func (c *Client) GetNodes(ctx context.Context) (*NodeInfoResponse, error) {
-	endpoint := fmt.Sprintf("%s/slurm/%s/nodes/", c.baseURL, c.apiVersion)
+	return c.GetNodesSince(ctx, 0)
+}
+
+// GetNodesSince retrieves Slurm node information updated after the given
+// UNIX timestamp. A zero timestamp retrieves all nodes.
+func (c *Client) GetNodesSince(ctx context.Context, updateTime int64) (*NodeInfoResponse, error) {
+	version, err := c.NegotiateVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/slurm/%s/nodes/", version)
+	if updateTime > 0 {
+		path += "?update_time=" + strconv.FormatInt(updateTime, 10)
+	}
+
+	body, err := c.get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeInfo, err := decodeNodes(version, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nodeInfo, nil
+}
+
+// get performs a GET request against slurmrestd and returns the response body.
+// Connection errors and 5xx responses are retried according to the retry policy.
+func (c *Client) get(ctx context.Context, path string) ([]byte, error) {
+	attempts := max(c.retryPolicy.MaxAttempts, 1)
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			delay := c.retryPolicy.backoff(attempt)
+			c.logger.Debug("Retrying Slurm API request", "url", path, "attempt", attempt+1, "delay", delay, "error", lastErr)
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, err
+			}
+		}
+
+		body, err := c.getOnce(ctx, path)
+		if err == nil {
+			return body, nil
+		}
+		lastErr = err
+		if !isRetryable(ctx, err) {
+			break
+		}
+	}
+
+	return nil, lastErr
+}
+
+// getOnce performs a single GET request.
+// When a dynamic token source is configured, a 401 response invalidates the
+// token and the request is retried once with a fresh token.
+func (c *Client) getOnce(ctx context.Context, path string) ([]byte, error) {
+	body, status, err := c.doGet(ctx, path)
+	if err == nil && status == http.StatusUnauthorized && c.tokenSource != nil {
+		c.logger.Info("Slurm API rejected token, reloading", "url", path)
+		c.tokenSource.Invalidate()
+		body, status, err = c.doGet(ctx, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		return nil, &StatusError{StatusCode: status, Body: string(body)}
+	}
+
+	return body, nil
+}
+
+// doGet executes a single GET request and returns the body and status code
+func (c *Client) doGet(ctx context.Context, path string) ([]byte, int, error) {
+	endpoint := c.baseURL + path
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Add JWT authentication headers
+	token := c.token
+	if c.tokenSource != nil {
+		token, err = c.tokenSource.Token()
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get token: %w", err)
+		}
+	}
 	if c.username != "" {
 		req.Header.Set("X-SLURM-USER-NAME", c.username)
 	}
-	if c.token != "" {
-		req.Header.Set("X-SLURM-USER-TOKEN", c.token)
+	if token != "" {
+		req.Header.Set("X-SLURM-USER-TOKEN", token)
 	}
 
-	c.logger.Debug("Requesting Slurm nodes", "url", endpoint)
+	c.logger.Debug("Requesting Slurm API", "url", endpoint)
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, 0, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
-	}
-
-	var nodeInfo NodeInfoResponse
-	if err := json.NewDecoder(resp.Body).Decode(&nodeInfo); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	return &nodeInfo, nil
+	return body, resp.StatusCode, nil
 }
@@ -4,9 +4,11 @@ import (
 	"context"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -141,13 +143,16 @@ func TestClient_GetNodes(t *testing.T) {
 			defer server.Close()
 
 			// Create client with test server URL
-			client := NewClient(
+			client, err := NewClient(
 				server.URL,
 				"v0.0.38",
 				"testuser",
 				"testtoken",
 				logger,
 			)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
 
 			// Call method being tested
 			resp, err := client.GetNodes(context.Background())
@@ -172,13 +177,16 @@ func TestNewClient(t *testing.T) {
 		Level: slog.LevelError,
 	}))
 
-	client := NewClient(
+	client, err := NewClient(
 		"http://example.com",
 		"v0.0.38",
 		"user",
 		"token",
 		logger,
 	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
 
 	if client.baseURL != "http://example.com" {
 		t.Errorf("baseURL = %s, want http://example.com", client.baseURL)
@@ -196,3 +204,106 @@ func TestNewClient(t *testing.T) {
 		t.Errorf("httpClient is nil")
 	}
 }
+
+func TestClient_GetNodes_UnixSocket(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	// Create a stand-in slurmrestd listening on a UNIX domain socket
+	socketPath := filepath.Join(t.TempDir(), "slurmrestd.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen on unix socket: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/slurm/v0.0.38/nodes/" {
+			t.Errorf("Unexpected request path: %s", r.URL.Path)
+		}
+		io.WriteString(w, `{"nodes": [{"name": "node1", "state": ["IDLE"], "partitions": ["compute"]}]}`)
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		endpoint string
+	}{
+		{name: "unix scheme with slashes", endpoint: "unix://" + socketPath},
+		{name: "unix scheme without slashes", endpoint: "unix:" + socketPath},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client, err := NewClient(tc.endpoint, "v0.0.38", "", "", logger)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			resp, err := client.GetNodes(context.Background())
+			if err != nil {
+				t.Fatalf("GetNodes() error = %v", err)
+			}
+			if len(resp.Nodes) != 1 || resp.Nodes[0].Name != "node1" {
+				t.Errorf("GetNodes() got invalid response: %+v", resp)
+			}
+		})
+	}
+}
+
+func TestParseUnixSocketURL(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		wantPath string
+		wantOK   bool
+	}{
+		{endpoint: "unix:///run/slurmrestd.sock", wantPath: "/run/slurmrestd.sock", wantOK: true},
+		{endpoint: "unix:/run/slurmrestd.sock", wantPath: "/run/slurmrestd.sock", wantOK: true},
+		{endpoint: "http://slurm-api:6820", wantPath: "", wantOK: false},
+	}
+
+	for _, tc := range tests {
+		path, ok := parseUnixSocketURL(tc.endpoint)
+		if path != tc.wantPath || ok != tc.wantOK {
+			t.Errorf("parseUnixSocketURL(%q) = (%q, %v), want (%q, %v)", tc.endpoint, path, ok, tc.wantPath, tc.wantOK)
+		}
+	}
+}
+
+func TestClient_GetNodesSince(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		io.WriteString(w, `{"nodes": [], "last_update": {"set": true, "infinite": false, "number": 1700000100}}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "v0.0.40", "", "", logger)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	resp, err := client.GetNodesSince(context.Background(), 1700000000)
+	if err != nil {
+		t.Fatalf("GetNodesSince() error = %v", err)
+	}
+	if query != "update_time=1700000000" {
+		t.Errorf("Unexpected query: %q", query)
+	}
+	if resp.LastUpdate == nil || resp.LastUpdate.Number != 1700000100 {
+		t.Errorf("Unexpected last_update: %+v", resp.LastUpdate)
+	}
+
+	if _, err := client.GetNodes(context.Background()); err != nil {
+		t.Fatalf("GetNodes() error = %v", err)
+	}
+	if query != "" {
+		t.Errorf("GetNodes() sent query %q, want none", query)
+	}
+}
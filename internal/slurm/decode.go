@@ -0,0 +1,70 @@
+package slurm
+
+import (
+	"encoding/json"
+)
+
+// normalizer is an item in a version-specific schema that normalizes to the model M
+type normalizer[M any] interface {
+	normalize() M
+}
+
+// listResponse is a slurmrestd list response with its items normalized.
+// The envelope is shared by all REST API versions.
+type listResponse[M any] struct {
+	Items      []M
+	LastUpdate *TimeValue
+	Meta       *Meta
+	Errors     []Error
+	Warnings   []Warning
+}
+
+// decodeVersioned decodes a list response whose items, found under key, use the schema T
+func decodeVersioned[T normalizer[M], M any](body []byte, key string) (*listResponse[M], error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+
+	var items []T
+	resp := &listResponse[M]{}
+	for name, value := range map[string]any{
+		key:           &items,
+		"last_update": &resp.LastUpdate,
+		"meta":        &resp.Meta,
+		"errors":      &resp.Errors,
+		"warnings":    &resp.Warnings,
+	} {
+		if data, ok := raw[name]; ok {
+			if err := json.Unmarshal(data, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	resp.Items = make([]M, 0, len(items))
+	for _, item := range items {
+		resp.Items = append(resp.Items, item.normalize())
+	}
+	return resp, nil
+}
+
+// versionDecoders maps REST API versions to decoders of a list response
+type versionDecoders[M any] map[string]func([]byte) (*listResponse[M], error)
+
+// decoderFor returns a decoder of list responses whose items, found under key, use the schema T
+func decoderFor[T normalizer[M], M any](key string) func([]byte) (*listResponse[M], error) {
+	return func(body []byte) (*listResponse[M], error) {
+		return decodeVersioned[T](body, key)
+	}
+}
+
+// decode decodes a response of the given version.
+// Unknown versions are decoded with the newest schema.
+func (d versionDecoders[M]) decode(version string, body []byte) (*listResponse[M], error) {
+	decode, ok := d[version]
+	if !ok {
+		decode = d[SupportedAPIVersions[0]]
+	}
+	return decode(body)
+}
@@ -0,0 +1,72 @@
+package slurm
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// runGoldenTest decodes the recorded response in testdata/<dir> of every
+// supported version and compares the normalized items with a golden file,
+// so that schema drift between versions is caught. It then checks that all
+// versions normalize to the same items once passed through comparable,
+// which clears fields whose values legitimately differ between fixtures.
+func runGoldenTest[M any](t *testing.T, dir string, decoders versionDecoders[M], comparable func([]M) []M) {
+	t.Helper()
+
+	var reference []M
+	for _, version := range SupportedAPIVersions {
+		input, err := os.ReadFile(filepath.Join("testdata", dir, version+".json"))
+		if err != nil {
+			t.Fatalf("Failed to read fixture: %v", err)
+		}
+		resp, err := decoders.decode(version, input)
+		if err != nil {
+			t.Fatalf("decode(%s) error = %v", version, err)
+		}
+
+		t.Run(version, func(t *testing.T) {
+			got, err := json.MarshalIndent(resp.Items, "", "  ")
+			if err != nil {
+				t.Fatalf("Failed to marshal %s: %v", dir, err)
+			}
+			got = append(got, '\n')
+
+			goldenPath := filepath.Join("testdata", dir, version+".golden.json")
+			if *update {
+				if err := os.WriteFile(goldenPath, got, 0644); err != nil {
+					t.Fatalf("Failed to update golden file: %v", err)
+				}
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("Failed to read golden file: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Normalized %s do not match %s:\ngot:\n%s\nwant:\n%s", dir, goldenPath, got, want)
+			}
+
+			if resp.Meta == nil || resp.Meta.Slurm == nil || resp.Meta.Slurm.Version == nil || resp.Meta.Slurm.Version.Major == "" {
+				t.Errorf("Slurm version metadata was not decoded: %+v", resp.Meta)
+			}
+		})
+
+		items := resp.Items
+		if comparable != nil {
+			items = comparable(items)
+		}
+		if reference == nil {
+			reference = items
+			continue
+		}
+		if !reflect.DeepEqual(items, reference) {
+			t.Errorf("%s normalized differently from %s:\ngot:  %+v\nwant: %+v", version, SupportedAPIVersions[0], items, reference)
+		}
+	}
+}
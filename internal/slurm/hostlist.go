@@ -0,0 +1,127 @@
+package slurm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxHostlistSize bounds the number of hosts a single expression may expand to
+const maxHostlistSize = 1 << 20
+
+// ExpandHostlist expands a Slurm hostlist expression such as
+// "gpu[001-064],login[1-2]" into the individual host names.
+// Ranges keep the zero padding of their lower bound, and several bracket
+// groups in one host expand to every combination, like Slurm does.
+func ExpandHostlist(expr string) ([]string, error) {
+	var hosts []string
+	for _, item := range splitHostlist(expr) {
+		if item == "" {
+			continue
+		}
+		expanded, err := expandHost(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hostlist %q: %w", expr, err)
+		}
+		hosts = append(hosts, expanded...)
+		if len(hosts) > maxHostlistSize {
+			return nil, fmt.Errorf("invalid hostlist %q: expands to more than %d hosts", expr, maxHostlistSize)
+		}
+	}
+	return hosts, nil
+}
+
+// splitHostlist splits a hostlist on the commas that are outside brackets
+func splitHostlist(expr string) []string {
+	var items []string
+	depth, start := 0, 0
+	for i, r := range expr {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, strings.TrimSpace(expr[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(items, strings.TrimSpace(expr[start:]))
+}
+
+// expandHost expands the bracket groups of a single host expression
+func expandHost(host string) ([]string, error) {
+	open := strings.IndexByte(host, '[')
+	if open < 0 {
+		if strings.ContainsRune(host, ']') {
+			return nil, fmt.Errorf("unbalanced brackets in %q", host)
+		}
+		return []string{host}, nil
+	}
+	end := strings.IndexByte(host[open:], ']')
+	if end < 0 {
+		return nil, fmt.Errorf("unbalanced brackets in %q", host)
+	}
+	end += open
+
+	prefix, ranges, rest := host[:open], host[open+1:end], host[end+1:]
+	if strings.ContainsAny(prefix, "]") || strings.ContainsRune(ranges, '[') {
+		return nil, fmt.Errorf("unbalanced brackets in %q", host)
+	}
+
+	values, err := expandRanges(ranges)
+	if err != nil {
+		return nil, err
+	}
+	suffixes, err := expandHost(rest)
+	if err != nil {
+		return nil, err
+	}
+	if len(values)*len(suffixes) > maxHostlistSize {
+		return nil, fmt.Errorf("%q expands to more than %d hosts", host, maxHostlistSize)
+	}
+
+	hosts := make([]string, 0, len(values)*len(suffixes))
+	for _, value := range values {
+		for _, suffix := range suffixes {
+			hosts = append(hosts, prefix+value+suffix)
+		}
+	}
+	return hosts, nil
+}
+
+// expandRanges expands the contents of a bracket group such as "001-003,010"
+func expandRanges(ranges string) ([]string, error) {
+	var values []string
+	for _, part := range strings.Split(ranges, ",") {
+		part = strings.TrimSpace(part)
+		lo, hi, isRange := strings.Cut(part, "-")
+		if !isRange {
+			hi = lo
+		}
+
+		start, err := strconv.ParseUint(lo, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q", part)
+		}
+		stop, err := strconv.ParseUint(hi, 10, 64)
+		if err != nil || stop < start {
+			return nil, fmt.Errorf("invalid range %q", part)
+		}
+		if stop-start >= maxHostlistSize-uint64(len(values)) {
+			return nil, fmt.Errorf("%q expands to more than %d hosts", ranges, maxHostlistSize)
+		}
+
+		// Stop explicitly at the upper bound, n++ would wrap around at MaxUint64
+		width := len(lo)
+		for n := start; ; n++ {
+			values = append(values, fmt.Sprintf("%0*d", width, n))
+			if n == stop {
+				break
+			}
+		}
+	}
+	return values, nil
+}
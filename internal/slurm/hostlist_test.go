@@ -0,0 +1,46 @@
+package slurm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandHostlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "single host", expr: "login01", want: []string{"login01"}},
+		{name: "plain list", expr: "login01, login02", want: []string{"login01", "login02"}},
+		{name: "zero padded range", expr: "gpu[008-011]", want: []string{"gpu008", "gpu009", "gpu010", "gpu011"}},
+		{name: "unpadded range", expr: "node[9-11]", want: []string{"node9", "node10", "node11"}},
+		{name: "ranges and values", expr: "gpu[01-02,07]", want: []string{"gpu01", "gpu02", "gpu07"}},
+		{name: "several items", expr: "gpu[1-2],login1", want: []string{"gpu1", "gpu2", "login1"}},
+		{name: "suffix after brackets", expr: "rack[1-2]-ib", want: []string{"rack1-ib", "rack2-ib"}},
+		{name: "several bracket groups", expr: "r[1-2]n[1-2]", want: []string{"r1n1", "r1n2", "r2n1", "r2n2"}},
+		{name: "empty", expr: "", want: nil},
+		{name: "unclosed bracket", expr: "gpu[1-2", wantErr: true},
+		{name: "stray closing bracket", expr: "gpu1-2]", wantErr: true},
+		{name: "nested brackets", expr: "gpu[1[2]]", wantErr: true},
+		{name: "reversed range", expr: "gpu[5-1]", wantErr: true},
+		{name: "non-numeric range", expr: "gpu[a-c]", wantErr: true},
+		{name: "too large", expr: "n[0-99999999]", wantErr: true},
+		{name: "largest number", expr: "n[18446744073709551615]", want: []string{"n18446744073709551615"}},
+		{name: "range up to the largest number", expr: "n[18446744073709551614-18446744073709551615]", want: []string{"n18446744073709551614", "n18446744073709551615"}},
+		{name: "too large in total", expr: "n[0-1048575,0-1048575,0-1048575]", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ExpandHostlist(tc.expr)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ExpandHostlist(%q) error = %v, wantErr %v", tc.expr, err, tc.wantErr)
+			}
+			if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ExpandHostlist(%q) = %v, want %v", tc.expr, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package slurm
+
+import (
+	"context"
+	"fmt"
+)
+
+// JobInfoResponse represents the Slurm job information response
+type JobInfoResponse struct {
+	Jobs       []Job      `json:"jobs"`
+	LastUpdate *TimeValue `json:"last_update,omitempty"`
+	Meta       *Meta      `json:"meta,omitempty"`
+	Errors     []Error    `json:"errors,omitempty"`
+	Warnings   []Warning  `json:"warnings,omitempty"`
+}
+
+// Job represents a Slurm job normalized across REST API versions
+type Job struct {
+	JobID     int64  `json:"job_id"`
+	Name      string `json:"name"`
+	UserName  string `json:"user_name"`
+	Account   string `json:"account"`
+	Partition string `json:"partition"`
+	QOS       string `json:"qos"`
+	// State holds the base state followed by any state flags, in upper case
+	State []string `json:"state"`
+	// Nodes is the hostlist expression of the allocated nodes, e.g. "gpu[001-004]"
+	Nodes string `json:"nodes,omitempty"`
+	// ArrayJobID is zero for jobs that are not part of a job array
+	ArrayJobID  int64  `json:"array_job_id,omitempty"`
+	ArrayTaskID *int64 `json:"array_task_id,omitempty"`
+	// HetJobID is zero for jobs that are not part of a heterogeneous job
+	HetJobID     int64  `json:"het_job_id,omitempty"`
+	HetJobOffset *int64 `json:"het_job_offset,omitempty"`
+	// Comment, AdminComment and Extra are free-form strings set with
+	// --comment, --admin-comment and --extra
+	Comment      string `json:"comment,omitempty"`
+	AdminComment string `json:"admin_comment,omitempty"`
+	Extra        string `json:"extra,omitempty"`
+}
+
+// IsRunning reports whether the base state of the job is RUNNING
+func (j Job) IsRunning() bool {
+	return len(j.State) > 0 && j.State[0] == "RUNNING"
+}
+
+// GetJobs retrieves the jobs known to slurmctld
+func (c *Client) GetJobs(ctx context.Context) (*JobInfoResponse, error) {
+	version, err := c.NegotiateVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.get(ctx, fmt.Sprintf("/slurm/%s/jobs/", version))
+	if err != nil {
+		return nil, err
+	}
+
+	jobInfo, err := decodeJobs(version, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return jobInfo, nil
+}
+
+// jobDecoders maps REST API versions to decoders of the /jobs/ response
+var jobDecoders = versionDecoders[Job]{
+	"v0.0.38": decoderFor[jobV0038, Job]("jobs"),
+	"v0.0.39": decoderFor[jobV0039, Job]("jobs"),
+	"v0.0.40": decoderFor[jobV0039, Job]("jobs"),
+	"v0.0.41": decoderFor[jobV0039, Job]("jobs"),
+	"v0.0.42": decoderFor[jobV0039, Job]("jobs"),
+}
+
+// decodeJobs decodes a /jobs/ response of the given version into the normalized model.
+// Unknown versions are decoded with the newest schema.
+func decodeJobs(version string, body []byte) (*JobInfoResponse, error) {
+	resp, err := jobDecoders.decode(version, body)
+	if err != nil {
+		return nil, err
+	}
+	return &JobInfoResponse{
+		Jobs:       resp.Items,
+		LastUpdate: resp.LastUpdate,
+		Meta:       resp.Meta,
+		Errors:     resp.Errors,
+		Warnings:   resp.Warnings,
+	}, nil
+}
+
+// noVal is the value older versions use for unset 32-bit integers
+const noVal = 0xfffffffe
+
+// jobV0038 is a job as returned by the openapi/v0.0.38 plugin.
+// The state is a plain string and unset integers are reported as NO_VAL.
+type jobV0038 struct {
+	JobID        int64   `json:"job_id"`
+	Name         string  `json:"name"`
+	UserName     string  `json:"user_name"`
+	Account      string  `json:"account"`
+	Partition    string  `json:"partition"`
+	QOS          string  `json:"qos"`
+	JobState     csvList `json:"job_state"`
+	Nodes        string  `json:"nodes"`
+	ArrayJobID   int64   `json:"array_job_id"`
+	ArrayTaskID  *int64  `json:"array_task_id"`
+	HetJobID     int64   `json:"het_job_id"`
+	HetJobOffset *int64  `json:"het_job_offset"`
+	Comment      string  `json:"comment"`
+	AdminComment string  `json:"admin_comment"`
+	Extra        string  `json:"extra"`
+}
+
+func (j jobV0038) normalize() Job {
+	// unsetNoVal drops NO_VAL and larger sentinel values
+	unsetNoVal := func(n *int64) *int64 {
+		if n == nil || *n >= noVal {
+			return nil
+		}
+		return n
+	}
+
+	return Job{
+		JobID:        j.JobID,
+		Name:         j.Name,
+		UserName:     j.UserName,
+		Account:      j.Account,
+		Partition:    j.Partition,
+		QOS:          j.QOS,
+		State:        normalizeState(j.JobState),
+		Nodes:        j.Nodes,
+		ArrayJobID:   j.ArrayJobID,
+		ArrayTaskID:  unsetNoVal(j.ArrayTaskID),
+		HetJobID:     j.HetJobID,
+		HetJobOffset: unsetNoVal(j.HetJobOffset),
+		Comment:      j.Comment,
+		AdminComment: j.AdminComment,
+		Extra:        j.Extra,
+	}
+}
+
+// jobV0039 is a job as returned by data_parser/v0.0.39 through v0.0.42.
+// The state is an array of base state and flags, and optional integers are
+// wrapped in no-val objects.
+type jobV0039 struct {
+	JobID        int64       `json:"job_id"`
+	Name         string      `json:"name"`
+	UserName     string      `json:"user_name"`
+	Account      string      `json:"account"`
+	Partition    string      `json:"partition"`
+	QOS          string      `json:"qos"`
+	JobState     csvList     `json:"job_state"`
+	Nodes        string      `json:"nodes"`
+	ArrayJobID   noValNumber `json:"array_job_id"`
+	ArrayTaskID  noValNumber `json:"array_task_id"`
+	HetJobID     noValNumber `json:"het_job_id"`
+	HetJobOffset noValNumber `json:"het_job_offset"`
+	Comment      string      `json:"comment"`
+	AdminComment string      `json:"admin_comment"`
+	Extra        string      `json:"extra"`
+}
+
+func (j jobV0039) normalize() Job {
+	return Job{
+		JobID:        j.JobID,
+		Name:         j.Name,
+		UserName:     j.UserName,
+		Account:      j.Account,
+		Partition:    j.Partition,
+		QOS:          j.QOS,
+		State:        normalizeState(j.JobState),
+		Nodes:        j.Nodes,
+		ArrayJobID:   j.ArrayJobID.value(),
+		ArrayTaskID:  j.ArrayTaskID.optional(),
+		HetJobID:     j.HetJobID.value(),
+		HetJobOffset: j.HetJobOffset.optional(),
+		Comment:      j.Comment,
+		AdminComment: j.AdminComment,
+		Extra:        j.Extra,
+	}
+}
@@ -0,0 +1,68 @@
+package slurm
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestDecodeJobs_Golden decodes a recorded /jobs/ response for every
+// supported version and compares the normalized result with a golden file
+func TestDecodeJobs_Golden(t *testing.T) {
+	runGoldenTest(t, "jobs", jobDecoders, nil)
+}
+
+func TestClient_GetJobs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	fixture, err := os.ReadFile(filepath.Join("testdata", "jobs", "v0.0.41.json"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	var requestedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(fixture)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "v0.0.41", "", "", logger)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	resp, err := client.GetJobs(context.Background())
+	if err != nil {
+		t.Fatalf("GetJobs() error = %v", err)
+	}
+	if requestedPath != "/slurm/v0.0.41/jobs/" {
+		t.Errorf("Requested path = %s, want /slurm/v0.0.41/jobs/", requestedPath)
+	}
+
+	var running []int64
+	for _, job := range resp.Jobs {
+		if job.IsRunning() {
+			running = append(running, job.JobID)
+		}
+	}
+	if !reflect.DeepEqual(running, []int64{1001, 1003, 1010}) {
+		t.Errorf("Running jobs = %v, want [1001 1003 1010]", running)
+	}
+
+	array := resp.Jobs[1]
+	if array.ArrayJobID != 1002 || array.ArrayTaskID == nil || *array.ArrayTaskID != 0 {
+		t.Errorf("Array job was not decoded: %+v", array)
+	}
+	if resp.Jobs[0].ArrayTaskID != nil || resp.Jobs[0].HetJobOffset != nil {
+		t.Errorf("Unset array and het fields should be nil: %+v", resp.Jobs[0])
+	}
+}
@@ -0,0 +1,120 @@
+package slurm
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultJWTLifespan is the lifespan of locally minted tokens when none is configured
+const DefaultJWTLifespan = 30 * time.Minute
+
+// WithJWTKeyFile mints tokens locally using the slurmctld jwt_hs256.key.
+// The client username is used as the "sun" claim, so it must be set.
+func WithJWTKeyFile(path string, lifespan time.Duration) ClientOption {
+	return func(c *Client) error {
+		ts, err := NewJWTTokenSource(path, c.username, lifespan)
+		if err != nil {
+			return err
+		}
+		c.tokenSource = ts
+		return nil
+	}
+}
+
+// JWTTokenSource is a TokenSource that signs short-lived HS256 tokens with
+// the same key that slurmctld uses (AuthAltParameters=jwt_key=...).
+// Tokens are renewed once 80% of their lifespan has elapsed.
+type JWTTokenSource struct {
+	key      []byte
+	username string
+	lifespan time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	renewAt time.Time
+}
+
+// NewJWTTokenSource creates a JWTTokenSource from a jwt_hs256.key file
+func NewJWTTokenSource(keyFile, username string, lifespan time.Duration) (*JWTTokenSource, error) {
+	if username == "" {
+		return nil, fmt.Errorf("username is required to mint Slurm tokens")
+	}
+	if lifespan <= 0 {
+		lifespan = DefaultJWTLifespan
+	}
+
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key file: %w", err)
+	}
+	// The key is raw random bytes; a trailing 0x0a or 0x0d is part of it
+	if len(key) == 0 {
+		return nil, fmt.Errorf("JWT key file %s is empty", keyFile)
+	}
+
+	return &JWTTokenSource{
+		key:      key,
+		username: username,
+		lifespan: lifespan,
+		now:      time.Now,
+	}, nil
+}
+
+// Token returns the current token, minting a new one when it is close to expiry
+func (ts *JWTTokenSource) Token() (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	now := ts.now()
+	if ts.token != "" && now.Before(ts.renewAt) {
+		return ts.token, nil
+	}
+
+	token, err := ts.sign(now)
+	if err != nil {
+		return "", err
+	}
+	ts.token = token
+	ts.renewAt = now.Add(ts.lifespan * 4 / 5)
+	return ts.token, nil
+}
+
+// Invalidate discards the cached token so that a new one is minted on the next call
+func (ts *JWTTokenSource) Invalidate() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.token = ""
+}
+
+// sign creates a signed HS256 token issued at the given time
+func (ts *JWTTokenSource) sign(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "HS256",
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JWT header: %w", err)
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Unix(),
+		"exp": now.Add(ts.lifespan).Unix(),
+		"sun": ts.username,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JWT claims: %w", err)
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+
+	mac := hmac.New(sha256.New, ts.key)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + enc.EncodeToString(mac.Sum(nil)), nil
+}
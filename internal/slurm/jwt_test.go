@@ -0,0 +1,107 @@
+package slurm
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJWTTokenSource(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "jwt_hs256.key")
+	writeFile(t, keyFile, []byte("0123456789abcdef0123456789abcdef"))
+
+	if _, err := NewJWTTokenSource(keyFile, "", time.Hour); err == nil {
+		t.Error("NewJWTTokenSource() expected error without username, got nil")
+	}
+	if _, err := NewJWTTokenSource(filepath.Join(dir, "missing.key"), "prometheus", time.Hour); err == nil {
+		t.Error("NewJWTTokenSource() expected error for missing key file, got nil")
+	}
+
+	ts, err := NewJWTTokenSource(keyFile, "prometheus", 10*time.Minute)
+	if err != nil {
+		t.Fatalf("NewJWTTokenSource() error = %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	ts.now = func() time.Time { return now }
+
+	token, err := ts.Token()
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+
+	// Verify the signature and claims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("Token() = %q, want three dot-separated parts", token)
+	}
+	mac := hmac.New(sha256.New, []byte("0123456789abcdef0123456789abcdef"))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if want := base64.RawURLEncoding.EncodeToString(mac.Sum(nil)); parts[2] != want {
+		t.Errorf("Token signature = %s, want %s", parts[2], want)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatalf("Failed to decode claims: %v", err)
+	}
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Sun string `json:"sun"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatalf("Failed to unmarshal claims: %v", err)
+	}
+	if claims.Sun != "prometheus" || claims.Iat != now.Unix() || claims.Exp != now.Add(10*time.Minute).Unix() {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+
+	// The token is reused until it is close to expiry
+	now = now.Add(5 * time.Minute)
+	if again, _ := ts.Token(); again != token {
+		t.Error("Token() minted a new token before the renewal point")
+	}
+	now = now.Add(4 * time.Minute)
+	if renewed, _ := ts.Token(); renewed == token {
+		t.Error("Token() did not renew the token before expiry")
+	}
+
+	// Invalidate forces a new token
+	current, _ := ts.Token()
+	now = now.Add(time.Second)
+	ts.Invalidate()
+	if renewed, _ := ts.Token(); renewed == current {
+		t.Error("Token() did not mint a new token after Invalidate()")
+	}
+}
+
+func TestJWTTokenSource_BinaryKey(t *testing.T) {
+	// Random keys may end in bytes that look like a line ending
+	key := []byte{0x8f, 0x00, 0x1b, 0xfe, 0x42, 0x0d, 0x0a}
+	keyFile := filepath.Join(t.TempDir(), "jwt_hs256.key")
+	writeFile(t, keyFile, key)
+
+	ts, err := NewJWTTokenSource(keyFile, "prometheus", time.Hour)
+	if err != nil {
+		t.Fatalf("NewJWTTokenSource() error = %v", err)
+	}
+	token, err := ts.Token()
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("Token() = %q, want three dot-separated parts", token)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if want := base64.RawURLEncoding.EncodeToString(mac.Sum(nil)); parts[2] != want {
+		t.Errorf("Token signature = %s, want the signature with the full key %s", parts[2], want)
+	}
+}
@@ -0,0 +1,227 @@
+package slurm
+
+import "strings"
+
+// nodeDecoders maps REST API versions to decoders of the /nodes/ response
+var nodeDecoders = versionDecoders[Node]{
+	"v0.0.38": decoderFor[nodeV0038, Node]("nodes"),
+	"v0.0.39": decoderFor[nodeV0039, Node]("nodes"),
+	"v0.0.40": decoderFor[nodeV0040, Node]("nodes"),
+	"v0.0.41": decoderFor[nodeV0040, Node]("nodes"),
+	"v0.0.42": decoderFor[nodeV0040, Node]("nodes"),
+}
+
+// decodeNodes decodes a /nodes/ response of the given version into the normalized model.
+// Unknown versions are decoded with the newest schema.
+func decodeNodes(version string, body []byte) (*NodeInfoResponse, error) {
+	resp, err := nodeDecoders.decode(version, body)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeInfoResponse{
+		Nodes:      resp.Items,
+		LastUpdate: resp.LastUpdate,
+		Meta:       resp.Meta,
+		Errors:     resp.Errors,
+		Warnings:   resp.Warnings,
+	}, nil
+}
+
+// compactNode replaces empty lists with nil so that an empty CSV string and
+// an empty array normalize to the same value
+func compactNode(node Node) Node {
+	for _, list := range []*[]string{&node.State, &node.Partitions, &node.Features, &node.ActiveFeatures} {
+		if len(*list) == 0 {
+			*list = nil
+		}
+	}
+	return node
+}
+
+// nodeV0038 is a node as returned by the openapi/v0.0.38 plugin.
+// The base state is a lower-case string with flags in a separate array,
+// features are comma-separated strings, numbers are not wrapped and the
+// slurmd version is reported as slurmd_version.
+// A state array is accepted as well for compatibility with proxies that
+// rewrite the response.
+type nodeV0038 struct {
+	Name            string  `json:"name"`
+	Address         string  `json:"address"`
+	Hostname        string  `json:"hostname"`
+	State           csvList `json:"state"`
+	StateFlags      csvList `json:"state_flags"`
+	Partitions      csvList `json:"partitions"`
+	Features        csvList `json:"features"`
+	ActiveFeatures  csvList `json:"active_features"`
+	CPUs            int64   `json:"cpus"`
+	RealMemory      int64   `json:"real_memory"`
+	FreeMemory      int64   `json:"free_memory"`
+	BootTime        int64   `json:"boot_time"`
+	Gres            string  `json:"gres"`
+	GresUsed        string  `json:"gres_used"`
+	Sockets         int64   `json:"sockets"`
+	Cores           int64   `json:"cores"`
+	Threads         int64   `json:"threads"`
+	Architecture    string  `json:"architecture"`
+	OperatingSystem string  `json:"operating_system"`
+	Reason          string  `json:"reason"`
+	Comment         string  `json:"comment"`
+	Extra           string  `json:"extra"`
+	SlurmdVersion   string  `json:"slurmd_version"`
+}
+
+func (n nodeV0038) normalize() Node {
+	return compactNode(Node{
+		Name:            n.Name,
+		Address:         n.Address,
+		Hostname:        n.Hostname,
+		State:           normalizeState(append(n.State, n.StateFlags...)),
+		Partitions:      n.Partitions,
+		Features:        n.Features,
+		ActiveFeatures:  n.ActiveFeatures,
+		CPUs:            n.CPUs,
+		RealMemory:      n.RealMemory,
+		FreeMemory:      n.FreeMemory,
+		BootTime:        n.BootTime,
+		Gres:            n.Gres,
+		GresUsed:        n.GresUsed,
+		Sockets:         n.Sockets,
+		Cores:           n.Cores,
+		Threads:         n.Threads,
+		Architecture:    n.Architecture,
+		OperatingSystem: n.OperatingSystem,
+		Reason:          n.Reason,
+		Comment:         n.Comment,
+		Extra:           n.Extra,
+		SlurmdVersion:   n.SlurmdVersion,
+	})
+}
+
+// nodeV0039 is a node as returned by data_parser/v0.0.39.
+// The state is an array of base state and flags, features are still
+// comma-separated strings and optional numbers are wrapped in no-val objects.
+type nodeV0039 struct {
+	Name            string      `json:"name"`
+	Address         string      `json:"address"`
+	Hostname        string      `json:"hostname"`
+	State           []string    `json:"state"`
+	Partitions      []string    `json:"partitions"`
+	Features        csvList     `json:"features"`
+	ActiveFeatures  csvList     `json:"active_features"`
+	CPUs            int64       `json:"cpus"`
+	RealMemory      int64       `json:"real_memory"`
+	FreeMemory      noValNumber `json:"free_mem"`
+	BootTime        noValNumber `json:"boot_time"`
+	Gres            string      `json:"gres"`
+	GresUsed        string      `json:"gres_used"`
+	Sockets         int64       `json:"sockets"`
+	Cores           int64       `json:"cores"`
+	Threads         int64       `json:"threads"`
+	Architecture    string      `json:"architecture"`
+	OperatingSystem string      `json:"operating_system"`
+	Reason          string      `json:"reason"`
+	Comment         string      `json:"comment"`
+	Extra           string      `json:"extra"`
+	ClusterName     string      `json:"cluster_name"`
+	Version         string      `json:"version"`
+}
+
+func (n nodeV0039) normalize() Node {
+	return compactNode(Node{
+		Name:            n.Name,
+		Address:         n.Address,
+		Hostname:        n.Hostname,
+		State:           normalizeState(n.State),
+		Partitions:      n.Partitions,
+		Features:        n.Features,
+		ActiveFeatures:  n.ActiveFeatures,
+		CPUs:            n.CPUs,
+		RealMemory:      n.RealMemory,
+		FreeMemory:      n.FreeMemory.value(),
+		BootTime:        n.BootTime.value(),
+		Gres:            n.Gres,
+		GresUsed:        n.GresUsed,
+		Sockets:         n.Sockets,
+		Cores:           n.Cores,
+		Threads:         n.Threads,
+		Architecture:    n.Architecture,
+		OperatingSystem: n.OperatingSystem,
+		Reason:          n.Reason,
+		Comment:         n.Comment,
+		Extra:           n.Extra,
+		ClusterName:     n.ClusterName,
+		SlurmdVersion:   n.Version,
+	})
+}
+
+// nodeV0040 is a node as returned by data_parser/v0.0.40 through v0.0.42.
+// Features became string arrays and instance_type was added; the fields
+// used here did not change between these versions.
+type nodeV0040 struct {
+	Name            string      `json:"name"`
+	Address         string      `json:"address"`
+	Hostname        string      `json:"hostname"`
+	State           []string    `json:"state"`
+	Partitions      []string    `json:"partitions"`
+	Features        []string    `json:"features"`
+	ActiveFeatures  []string    `json:"active_features"`
+	CPUs            int64       `json:"cpus"`
+	RealMemory      int64       `json:"real_memory"`
+	FreeMemory      noValNumber `json:"free_mem"`
+	BootTime        noValNumber `json:"boot_time"`
+	Gres            string      `json:"gres"`
+	GresUsed        string      `json:"gres_used"`
+	Sockets         int64       `json:"sockets"`
+	Cores           int64       `json:"cores"`
+	Threads         int64       `json:"threads"`
+	Architecture    string      `json:"architecture"`
+	OperatingSystem string      `json:"operating_system"`
+	Reason          string      `json:"reason"`
+	Comment         string      `json:"comment"`
+	Extra           string      `json:"extra"`
+	ClusterName     string      `json:"cluster_name"`
+	InstanceType    string      `json:"instance_type"`
+	Version         string      `json:"version"`
+}
+
+func (n nodeV0040) normalize() Node {
+	return compactNode(Node{
+		Name:            n.Name,
+		Address:         n.Address,
+		Hostname:        n.Hostname,
+		State:           normalizeState(n.State),
+		Partitions:      n.Partitions,
+		Features:        n.Features,
+		ActiveFeatures:  n.ActiveFeatures,
+		CPUs:            n.CPUs,
+		RealMemory:      n.RealMemory,
+		FreeMemory:      n.FreeMemory.value(),
+		BootTime:        n.BootTime.value(),
+		Gres:            n.Gres,
+		GresUsed:        n.GresUsed,
+		Sockets:         n.Sockets,
+		Cores:           n.Cores,
+		Threads:         n.Threads,
+		Architecture:    n.Architecture,
+		OperatingSystem: n.OperatingSystem,
+		Reason:          n.Reason,
+		Comment:         n.Comment,
+		Extra:           n.Extra,
+		ClusterName:     n.ClusterName,
+		InstanceType:    n.InstanceType,
+		SlurmdVersion:   n.Version,
+	})
+}
+
+// normalizeState upper-cases states and splits combined "IDLE+DRAIN" forms
+func normalizeState(states []string) []string {
+	var normalized []string
+	for _, state := range states {
+		for _, part := range strings.Split(state, "+") {
+			if part = strings.TrimSpace(part); part != "" {
+				normalized = append(normalized, strings.ToUpper(part))
+			}
+		}
+	}
+	return normalized
+}
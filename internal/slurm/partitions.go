@@ -0,0 +1,169 @@
+package slurm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PartitionInfoResponse represents the Slurm partition information response
+type PartitionInfoResponse struct {
+	Partitions []Partition `json:"partitions"`
+	LastUpdate *TimeValue  `json:"last_update,omitempty"`
+	Meta       *Meta       `json:"meta,omitempty"`
+	Errors     []Error     `json:"errors,omitempty"`
+	Warnings   []Warning   `json:"warnings,omitempty"`
+}
+
+// Partition represents a Slurm partition normalized across REST API versions
+type Partition struct {
+	Name string `json:"name"`
+	// State is the partition state in upper case, such as UP, DOWN, DRAIN or INACTIVE
+	State string `json:"state"`
+	// QOS is the QOS attached to the partition
+	QOS string `json:"qos,omitempty"`
+	// MaxTime is the maximum run time of jobs in minutes, nil when unlimited
+	MaxTime *int64 `json:"max_time,omitempty"`
+	// DefaultMemoryPerCPU and DefaultMemoryPerNode are the default memory
+	// of jobs in megabytes, zero when unset
+	DefaultMemoryPerCPU  int64 `json:"default_memory_per_cpu,omitempty"`
+	DefaultMemoryPerNode int64 `json:"default_memory_per_node,omitempty"`
+	TotalNodes           int64 `json:"total_nodes,omitempty"`
+	// Nodes is the hostlist expression of the partition's nodes
+	Nodes string `json:"nodes,omitempty"`
+	// BillingWeights is the TRESBillingWeights of the partition, e.g. "CPU=1.0,Mem=0.25G"
+	BillingWeights string `json:"billing_weights,omitempty"`
+}
+
+// IsUp reports whether the partition accepts and runs jobs
+func (p Partition) IsUp() bool {
+	return p.State == "UP"
+}
+
+// GetPartitions retrieves the partitions known to slurmctld
+func (c *Client) GetPartitions(ctx context.Context) (*PartitionInfoResponse, error) {
+	version, err := c.NegotiateVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.get(ctx, fmt.Sprintf("/slurm/%s/partitions/", version))
+	if err != nil {
+		return nil, err
+	}
+
+	partitionInfo, err := decodePartitions(version, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return partitionInfo, nil
+}
+
+// partitionDecoders maps REST API versions to decoders of the /partitions/ response
+var partitionDecoders = versionDecoders[Partition]{
+	"v0.0.38": decoderFor[partitionV0038, Partition]("partitions"),
+	"v0.0.39": decoderFor[partitionV0039, Partition]("partitions"),
+	"v0.0.40": decoderFor[partitionV0039, Partition]("partitions"),
+	"v0.0.41": decoderFor[partitionV0039, Partition]("partitions"),
+	"v0.0.42": decoderFor[partitionV0039, Partition]("partitions"),
+}
+
+// decodePartitions decodes a /partitions/ response of the given version into the normalized model.
+// Unknown versions are decoded with the newest schema.
+func decodePartitions(version string, body []byte) (*PartitionInfoResponse, error) {
+	resp, err := partitionDecoders.decode(version, body)
+	if err != nil {
+		return nil, err
+	}
+	return &PartitionInfoResponse{
+		Partitions: resp.Items,
+		LastUpdate: resp.LastUpdate,
+		Meta:       resp.Meta,
+		Errors:     resp.Errors,
+		Warnings:   resp.Warnings,
+	}, nil
+}
+
+// partitionState returns the first state of a partition in upper case
+func partitionState(states csvList) string {
+	if len(states) == 0 {
+		return ""
+	}
+	return strings.ToUpper(strings.TrimSpace(states[0]))
+}
+
+// partitionV0038 is a partition as returned by the openapi/v0.0.38 plugin.
+// Numbers are not wrapped, and an unlimited time is reported as INFINITE,
+// which is larger than NO_VAL.
+type partitionV0038 struct {
+	Name                 string  `json:"name"`
+	State                csvList `json:"state"`
+	QOS                  string  `json:"qos"`
+	MaxTimeLimit         *int64  `json:"max_time_limit"`
+	DefaultMemoryPerCPU  int64   `json:"default_memory_per_cpu"`
+	DefaultMemoryPerNode int64   `json:"default_memory_per_node"`
+	TotalNodes           int64   `json:"total_nodes"`
+	Nodes                string  `json:"nodes"`
+	BillingWeights       string  `json:"billing_weights"`
+}
+
+func (p partitionV0038) normalize() Partition {
+	maxTime := p.MaxTimeLimit
+	if maxTime != nil && *maxTime >= noVal {
+		maxTime = nil
+	}
+
+	return Partition{
+		Name:                 p.Name,
+		State:                partitionState(p.State),
+		QOS:                  p.QOS,
+		MaxTime:              maxTime,
+		DefaultMemoryPerCPU:  p.DefaultMemoryPerCPU,
+		DefaultMemoryPerNode: p.DefaultMemoryPerNode,
+		TotalNodes:           p.TotalNodes,
+		Nodes:                p.Nodes,
+		BillingWeights:       p.BillingWeights,
+	}
+}
+
+// partitionV0039 is a partition as returned by data_parser/v0.0.39 through
+// v0.0.42. Attributes are grouped in nested objects and optional numbers are
+// wrapped in no-val objects.
+type partitionV0039 struct {
+	Name  string `json:"name"`
+	Nodes struct {
+		Configured string      `json:"configured"`
+		Total      noValNumber `json:"total"`
+	} `json:"nodes"`
+	QOS struct {
+		Assigned string `json:"assigned"`
+	} `json:"qos"`
+	Defaults struct {
+		PartitionMemoryPerCPU  noValNumber `json:"partition_memory_per_cpu"`
+		PartitionMemoryPerNode noValNumber `json:"partition_memory_per_node"`
+	} `json:"defaults"`
+	Maximums struct {
+		Time noValNumber `json:"time"`
+	} `json:"maximums"`
+	Partition struct {
+		State csvList `json:"state"`
+	} `json:"partition"`
+	TRES struct {
+		BillingWeights string `json:"billing_weights"`
+	} `json:"tres"`
+}
+
+func (p partitionV0039) normalize() Partition {
+	return Partition{
+		Name:                 p.Name,
+		State:                partitionState(p.Partition.State),
+		QOS:                  p.QOS.Assigned,
+		MaxTime:              p.Maximums.Time.optional(),
+		DefaultMemoryPerCPU:  p.Defaults.PartitionMemoryPerCPU.value(),
+		DefaultMemoryPerNode: p.Defaults.PartitionMemoryPerNode.value(),
+		TotalNodes:           p.Nodes.Total.value(),
+		Nodes:                p.Nodes.Configured,
+		BillingWeights:       p.TRES.BillingWeights,
+	}
+}
@@ -0,0 +1,68 @@
+package slurm
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestDecodePartitions_Golden decodes a recorded /partitions/ response for
+// every supported version and compares the normalized result with a golden file
+func TestDecodePartitions_Golden(t *testing.T) {
+	runGoldenTest(t, "partitions", partitionDecoders, nil)
+}
+
+func TestClient_GetPartitions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	fixture, err := os.ReadFile(filepath.Join("testdata", "partitions", "v0.0.41.json"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	var requestedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(fixture)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "v0.0.41", "", "", logger)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	resp, err := client.GetPartitions(context.Background())
+	if err != nil {
+		t.Fatalf("GetPartitions() error = %v", err)
+	}
+	if requestedPath != "/slurm/v0.0.41/partitions/" {
+		t.Errorf("Requested path = %s, want /slurm/v0.0.41/partitions/", requestedPath)
+	}
+
+	var up []string
+	for _, partition := range resp.Partitions {
+		if partition.IsUp() {
+			up = append(up, partition.Name)
+		}
+	}
+	if !reflect.DeepEqual(up, []string{"compute", "gpu"}) {
+		t.Errorf("Partitions up = %v, want [compute gpu]", up)
+	}
+
+	compute, gpu := resp.Partitions[0], resp.Partitions[1]
+	if compute.MaxTime == nil || *compute.MaxTime != 1440 || compute.DefaultMemoryPerCPU != 4000 {
+		t.Errorf("Partition limits were not decoded: %+v", compute)
+	}
+	if gpu.MaxTime != nil || gpu.DefaultMemoryPerNode != 64000 {
+		t.Errorf("Unlimited time and memory per node were not decoded: %+v", gpu)
+	}
+}
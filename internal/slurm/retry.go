@@ -0,0 +1,93 @@
+package slurm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"time"
+)
+
+// RetryPolicy controls how failed Slurm API requests are retried.
+// Connection errors and 5xx responses are retried; 4xx responses are not.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one
+	MaxAttempts int
+	// BaseBackoff is the upper bound of the delay before the first retry
+	BaseBackoff time.Duration
+	// MaxBackoff caps the exponentially growing delay
+	MaxBackoff time.Duration
+}
+
+// WithRetryPolicy sets the retry policy of the client
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return func(c *Client) error {
+		if p.MaxAttempts < 1 {
+			return fmt.Errorf("retry max attempts must be at least 1, got %d", p.MaxAttempts)
+		}
+		c.retryPolicy = p
+		return nil
+	}
+}
+
+// backoff returns the delay before the given retry attempt (1-based) using
+// exponential backoff with full jitter
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	if p.BaseBackoff <= 0 {
+		return 0
+	}
+
+	ceiling := p.BaseBackoff
+	for i := 1; i < attempt; i++ {
+		ceiling *= 2
+		if p.MaxBackoff > 0 && ceiling >= p.MaxBackoff {
+			ceiling = p.MaxBackoff
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && ceiling > p.MaxBackoff {
+		ceiling = p.MaxBackoff
+	}
+
+	return rand.N(ceiling) + 1
+}
+
+// StatusError is returned when slurmrestd responds with an unexpected status code
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d, body: %s", e.StatusCode, e.Body)
+}
+
+// isRetryable reports whether a failed request should be retried
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+
+	// Transport failures such as refused connections or timeouts
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// sleepContext waits for the given duration or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,116 @@
+package slurm
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_GetNodes_Retry(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	tests := []struct {
+		name         string
+		failStatus   int
+		failures     int32
+		wantErr      bool
+		wantRequests int32
+	}{
+		{name: "5xx is retried until success", failStatus: http.StatusServiceUnavailable, failures: 2, wantErr: false, wantRequests: 3},
+		{name: "5xx gives up after max attempts", failStatus: http.StatusInternalServerError, failures: 10, wantErr: true, wantRequests: 3},
+		{name: "4xx is not retried", failStatus: http.StatusNotFound, failures: 10, wantErr: true, wantRequests: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if requests.Add(1) <= tc.failures {
+					w.WriteHeader(tc.failStatus)
+					return
+				}
+				io.WriteString(w, `{"nodes": []}`)
+			}))
+			defer server.Close()
+
+			client, err := NewClient(server.URL, "v0.0.40", "", "", logger, WithRetryPolicy(RetryPolicy{
+				MaxAttempts: 3,
+				BaseBackoff: time.Millisecond,
+				MaxBackoff:  5 * time.Millisecond,
+			}))
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			_, err = client.GetNodes(context.Background())
+			if (err != nil) != tc.wantErr {
+				t.Errorf("GetNodes() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got := requests.Load(); got != tc.wantRequests {
+				t.Errorf("Server received %d requests, want %d", got, tc.wantRequests)
+			}
+		})
+	}
+}
+
+func TestClient_GetNodes_RetryConnectionError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	// Grab a free port and close the server so that connections are refused
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	client, err := NewClient(url, "v0.0.40", "", "", logger, WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 2,
+		BaseBackoff: time.Millisecond,
+	}))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	_, err = client.GetNodes(context.Background())
+	if err == nil {
+		t.Fatal("GetNodes() expected error, got nil")
+	}
+	if !isRetryable(context.Background(), err) {
+		t.Errorf("Connection error %v should be retryable", err)
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for attempt := 1; attempt <= 8; attempt++ {
+		ceiling := p.BaseBackoff << (attempt - 1)
+		if ceiling > p.MaxBackoff {
+			ceiling = p.MaxBackoff
+		}
+		for i := 0; i < 20; i++ {
+			if d := p.backoff(attempt); d <= 0 || d > ceiling {
+				t.Fatalf("backoff(%d) = %v, want within (0, %v]", attempt, d, ceiling)
+			}
+		}
+	}
+
+	if d := (RetryPolicy{}).backoff(1); d != 0 {
+		t.Errorf("backoff without base = %v, want 0", d)
+	}
+}
+
+func TestWithRetryPolicy_Invalid(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if _, err := NewClient("http://example.com", "v0.0.40", "", "", logger, WithRetryPolicy(RetryPolicy{})); err == nil {
+		t.Error("NewClient() expected error for zero max attempts, got nil")
+	}
+}
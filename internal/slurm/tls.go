@@ -0,0 +1,149 @@
+package slurm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLSConfig represents TLS settings for connections to slurmrestd
+type TLSConfig struct {
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// files returns the certificate files referenced by the configuration
+func (c TLSConfig) files() []string {
+	var files []string
+	for _, f := range []string{c.CAFile, c.CertFile, c.KeyFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// build creates a tls.Config by reading the certificate files from disk
+func (c TLSConfig) build() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if c.CAFile != "" {
+		caPEM, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, fmt.Errorf("both cert_file and key_file must be specified for client authentication")
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// tlsTransport is an http.RoundTripper that rebuilds the underlying transport
+// whenever one of the configured certificate files changes on disk
+type tlsTransport struct {
+	config TLSConfig
+	base   *http.Transport
+	logger *slog.Logger
+
+	mu        sync.Mutex
+	transport *http.Transport
+	modTimes  map[string]time.Time
+}
+
+// newTLSTransport creates a tlsTransport and loads the initial certificates
+func newTLSTransport(cfg TLSConfig, base *http.Transport, logger *slog.Logger) (*tlsTransport, error) {
+	t := &tlsTransport{
+		config: cfg,
+		base:   base,
+		logger: logger,
+	}
+	if err := t.reload(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// RoundTrip executes a single HTTP transaction, reloading certificates first if needed
+func (t *tlsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	if t.changed() {
+		if err := t.reload(); err != nil {
+			// Keep using the previous certificates until the files become valid again
+			t.logger.Warn("Failed to reload TLS certificates", "error", err)
+		} else {
+			t.logger.Info("Reloaded TLS certificates")
+		}
+	}
+	transport := t.transport
+	t.mu.Unlock()
+
+	return transport.RoundTrip(req)
+}
+
+// changed reports whether any certificate file was modified since the last load
+func (t *tlsTransport) changed() bool {
+	for _, f := range t.config.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(t.modTimes[f]) {
+			return true
+		}
+	}
+	return false
+}
+
+// reload rebuilds the underlying transport from the certificate files
+func (t *tlsTransport) reload() error {
+	modTimes := make(map[string]time.Time)
+	for _, f := range t.config.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", f, err)
+		}
+		modTimes[f] = info.ModTime()
+	}
+
+	tlsConfig, err := t.config.build()
+	if err != nil {
+		return err
+	}
+
+	transport := t.base.Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	if t.transport != nil {
+		t.transport.CloseIdleConnections()
+	}
+	t.transport = transport
+	t.modTimes = modTimes
+	return nil
+}
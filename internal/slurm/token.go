@@ -0,0 +1,104 @@
+package slurm
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenSource provides JWT tokens for the X-SLURM-USER-TOKEN header
+type TokenSource interface {
+	// Token returns the token to use for the next request
+	Token() (string, error)
+	// Invalidate discards the cached token, e.g. after the server rejected it
+	Invalidate()
+}
+
+// WithTokenSource sets a dynamic token source that takes precedence over the static token
+func WithTokenSource(ts TokenSource) ClientOption {
+	return func(c *Client) error {
+		c.tokenSource = ts
+		return nil
+	}
+}
+
+// WithTokenFile reads the token from a file and re-reads it whenever the file changes
+func WithTokenFile(path string) ClientOption {
+	return func(c *Client) error {
+		ts, err := NewFileTokenSource(path)
+		if err != nil {
+			return err
+		}
+		c.tokenSource = ts
+		return nil
+	}
+}
+
+// FileTokenSource is a TokenSource that reads the token from a file.
+// The file may contain either the bare token or the "SLURM_JWT=<token>"
+// line printed by `scontrol token`.
+type FileTokenSource struct {
+	path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+}
+
+// NewFileTokenSource creates a FileTokenSource and reads the initial token
+func NewFileTokenSource(path string) (*FileTokenSource, error) {
+	ts := &FileTokenSource{path: path}
+	if _, err := ts.Token(); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// Token returns the cached token, re-reading the file if it has been modified
+func (ts *FileTokenSource) Token() (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	info, err := os.Stat(ts.path)
+	if err != nil {
+		if ts.token != "" {
+			// Keep serving the last token while the file is being replaced
+			return ts.token, nil
+		}
+		return "", fmt.Errorf("failed to stat token file: %w", err)
+	}
+	if ts.token != "" && info.ModTime().Equal(ts.modTime) {
+		return ts.token, nil
+	}
+
+	data, err := os.ReadFile(ts.path)
+	if err != nil {
+		if ts.token != "" {
+			return ts.token, nil
+		}
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	token = strings.TrimPrefix(token, "SLURM_JWT=")
+	if token == "" {
+		if ts.token != "" {
+			// A shell redirect truncates the file before writing the new token.
+			// modTime is left alone so the file is read again on the next call.
+			return ts.token, nil
+		}
+		return "", fmt.Errorf("token file %s is empty", ts.path)
+	}
+
+	ts.token = token
+	ts.modTime = info.ModTime()
+	return ts.token, nil
+}
+
+// Invalidate forces the token file to be re-read on the next call to Token
+func (ts *FileTokenSource) Invalidate() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.modTime = time.Time{}
+}
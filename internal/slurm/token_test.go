@@ -0,0 +1,125 @@
+package slurm
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileTokenSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slurm.jwt")
+
+	// Missing file is an error on construction
+	if _, err := NewFileTokenSource(path); err == nil {
+		t.Error("NewFileTokenSource() expected error for missing file, got nil")
+	}
+
+	// scontrol token output format
+	writeFile(t, path, []byte("SLURM_JWT=token1\n"))
+	ts, err := NewFileTokenSource(path)
+	if err != nil {
+		t.Fatalf("NewFileTokenSource() error = %v", err)
+	}
+	if token, _ := ts.Token(); token != "token1" {
+		t.Errorf("Token() = %q, want token1", token)
+	}
+
+	// Rotated token is picked up once the file changes
+	writeFile(t, path, []byte("token2"))
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatalf("Failed to update mtime: %v", err)
+	}
+	if token, _ := ts.Token(); token != "token2" {
+		t.Errorf("Token() after rotation = %q, want token2", token)
+	}
+
+	// The last token keeps being served while the file is missing
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove token file: %v", err)
+	}
+	if token, err := ts.Token(); err != nil || token != "token2" {
+		t.Errorf("Token() with missing file = (%q, %v), want (token2, nil)", token, err)
+	}
+}
+
+func TestFileTokenSource_TruncatedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slurm.jwt")
+	writeFile(t, path, []byte("SLURM_JWT=token1\n"))
+	ts, err := NewFileTokenSource(path)
+	if err != nil {
+		t.Fatalf("NewFileTokenSource() error = %v", err)
+	}
+
+	// A redirect empties the file before scontrol writes the new token
+	writeFile(t, path, nil)
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatalf("Failed to update mtime: %v", err)
+	}
+	if token, err := ts.Token(); err != nil || token != "token1" {
+		t.Errorf("Token() with empty file = (%q, %v), want (token1, nil)", token, err)
+	}
+
+	// The new token is read once written, even with the same mtime
+	writeFile(t, path, []byte("SLURM_JWT=token2\n"))
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatalf("Failed to update mtime: %v", err)
+	}
+	if token, _ := ts.Token(); token != "token2" {
+		t.Errorf("Token() after rewrite = %q, want token2", token)
+	}
+}
+
+func TestClient_GetNodes_ReloadsTokenOnUnauthorized(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("X-SLURM-USER-TOKEN") != "fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, `{"nodes": [{"name": "node1", "state": ["IDLE"], "partitions": ["compute"]}]}`)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "slurm.jwt")
+	writeFile(t, path, []byte("stale"))
+
+	client, err := NewClient(server.URL, "v0.0.38", "testuser", "", logger, WithTokenFile(path))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	// Rotate the token without touching the mtime so that only the 401 triggers a reload
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat token file: %v", err)
+	}
+	writeFile(t, path, []byte("fresh"))
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatalf("Failed to restore mtime: %v", err)
+	}
+
+	resp, err := client.GetNodes(context.Background())
+	if err != nil {
+		t.Fatalf("GetNodes() error = %v", err)
+	}
+	if len(resp.Nodes) != 1 {
+		t.Errorf("GetNodes() got %d nodes, want 1", len(resp.Nodes))
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("Server received %d requests, want 2", got)
+	}
+}
@@ -0,0 +1,177 @@
+package slurm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// noValNumber is an integer that data_parser v0.0.39 and later wrap in a
+// {"set", "infinite", "number"} object. Plain JSON numbers, as returned by
+// older versions, are accepted as well.
+type noValNumber struct {
+	Number   int64
+	Set      bool
+	Infinite bool
+}
+
+// UnmarshalJSON decodes either a plain number or a no-val object
+func (n *noValNumber) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*n = noValNumber{}
+		return nil
+	}
+	if len(data) > 0 && data[0] == '{' {
+		var obj struct {
+			Number   json.Number `json:"number"`
+			Set      bool        `json:"set"`
+			Infinite bool        `json:"infinite"`
+		}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		number, err := parseJSONNumber(obj.Number)
+		if err != nil {
+			return err
+		}
+		*n = noValNumber{Number: number, Set: obj.Set, Infinite: obj.Infinite}
+		return nil
+	}
+
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+	value, err := parseJSONNumber(number)
+	if err != nil {
+		return err
+	}
+	*n = noValNumber{Number: value, Set: true}
+	return nil
+}
+
+// value returns the number, or zero when it is unset or infinite
+func (n noValNumber) value() int64 {
+	if !n.Set || n.Infinite {
+		return 0
+	}
+	return n.Number
+}
+
+// optional returns the number, or nil when it is unset or infinite, for
+// fields where zero is a valid value
+func (n noValNumber) optional() *int64 {
+	if !n.Set || n.Infinite {
+		return nil
+	}
+	number := n.Number
+	return &number
+}
+
+// parseJSONNumber converts a JSON number to int64, truncating any fraction
+func parseJSONNumber(number json.Number) (int64, error) {
+	if number == "" {
+		return 0, nil
+	}
+	if i, err := number.Int64(); err == nil {
+		return i, nil
+	}
+	f, err := number.Float64()
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q: %w", number, err)
+	}
+	return int64(f), nil
+}
+
+// csvList is a list of strings that older versions return as a
+// comma-separated string and newer versions return as an array
+type csvList []string
+
+// UnmarshalJSON decodes either a comma-separated string or an array of strings
+func (l *csvList) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*l = nil
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*l = splitList(s)
+		return nil
+	}
+
+	var items []string
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	*l = items
+	return nil
+}
+
+// splitList splits a comma-separated string, dropping empty elements
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// UnmarshalJSON decodes a TimeValue from either a plain UNIX timestamp or a no-val object
+func (t *TimeValue) UnmarshalJSON(data []byte) error {
+	var n noValNumber
+	if err := n.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*t = TimeValue{Number: n.Number, Set: n.Set, Infinite: n.Infinite}
+	return nil
+}
+
+// UnmarshalJSON decodes version numbers that older versions return as integers
+func (v *VersionInfo) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Major json.RawMessage `json:"major"`
+		Minor json.RawMessage `json:"minor"`
+		Micro json.RawMessage `json:"micro"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var err error
+	if v.Major, err = versionComponent(raw.Major); err != nil {
+		return err
+	}
+	if v.Minor, err = versionComponent(raw.Minor); err != nil {
+		return err
+	}
+	if v.Micro, err = versionComponent(raw.Micro); err != nil {
+		return err
+	}
+	return nil
+}
+
+// versionComponent decodes a version component given as a string or a number
+func versionComponent(data json.RawMessage) (string, error) {
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return "", nil
+	}
+	if data[0] == '"' {
+		var s string
+		err := json.Unmarshal(data, &s)
+		return s, err
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(n, 10), nil
+}
@@ -0,0 +1,101 @@
+package slurm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// AutoAPIVersion selects the newest REST API version supported by both
+// slurmrestd and this client
+const AutoAPIVersion = "auto"
+
+// SupportedAPIVersions lists the REST API versions this client can decode, newest first
+var SupportedAPIVersions = []string{
+	"v0.0.42",
+	"v0.0.41",
+	"v0.0.40",
+	"v0.0.39",
+	"v0.0.38",
+}
+
+// openAPIPathPattern extracts the API version from OpenAPI path entries
+var openAPIPathPattern = regexp.MustCompile(`^/slurm/(v\d+\.\d+\.\d+)/nodes/?$`)
+
+// APIVersion returns the REST API version in use.
+// It returns "auto" until a version has been negotiated.
+func (c *Client) APIVersion() string {
+	c.versionMu.Lock()
+	defer c.versionMu.Unlock()
+	return c.apiVersion
+}
+
+// NegotiateVersion resolves the "auto" API version by probing slurmrestd.
+// It returns the configured version unchanged when it is not "auto".
+func (c *Client) NegotiateVersion(ctx context.Context) (string, error) {
+	// Serialize negotiation without blocking readers of APIVersion
+	c.negotiateMu.Lock()
+	defer c.negotiateMu.Unlock()
+
+	if current := c.APIVersion(); current != AutoAPIVersion {
+		return current, nil
+	}
+
+	version, err := c.probeOpenAPI(ctx)
+	if err != nil {
+		c.logger.Debug("OpenAPI probe failed, trying known versions", "error", err)
+		version, err = c.probePing(ctx)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to negotiate Slurm REST API version: %w", err)
+	}
+
+	c.logger.Info("Negotiated Slurm REST API version", "version", version)
+	c.versionMu.Lock()
+	c.apiVersion = version
+	c.versionMu.Unlock()
+	return version, nil
+}
+
+// probeOpenAPI picks the newest supported version advertised in /openapi/v3
+func (c *Client) probeOpenAPI(ctx context.Context) (string, error) {
+	body, err := c.get(ctx, "/openapi/v3")
+	if err != nil {
+		return "", err
+	}
+
+	var spec struct {
+		Paths map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(body, &spec); err != nil {
+		return "", fmt.Errorf("failed to decode OpenAPI specification: %w", err)
+	}
+
+	advertised := make(map[string]bool)
+	for path := range spec.Paths {
+		if m := openAPIPathPattern.FindStringSubmatch(path); m != nil {
+			advertised[m[1]] = true
+		}
+	}
+
+	for _, version := range SupportedAPIVersions {
+		if advertised[version] {
+			return version, nil
+		}
+	}
+	return "", fmt.Errorf("no supported version found in OpenAPI specification")
+}
+
+// probePing tries the ping endpoint of each supported version, newest first
+func (c *Client) probePing(ctx context.Context) (string, error) {
+	for _, version := range SupportedAPIVersions {
+		if _, err := c.get(ctx, fmt.Sprintf("/slurm/%s/ping/", version)); err == nil {
+			return version, nil
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+	}
+	return "", fmt.Errorf("no supported version responded to ping")
+}
@@ -0,0 +1,123 @@
+package slurm
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestClient_NegotiateVersion(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		wantVersion string
+		wantErr     bool
+	}{
+		{
+			name: "highest supported version from openapi",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/openapi/v3" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				io.WriteString(w, `{"paths": {
+					"/slurm/v0.0.39/nodes/": {},
+					"/slurm/v0.0.41/nodes/": {},
+					"/slurm/v0.0.41/jobs/": {},
+					"/slurm/v9.9.99/nodes/": {},
+					"/slurmdb/v0.0.41/jobs/": {}
+				}}`)
+			},
+			wantVersion: "v0.0.41",
+		},
+		{
+			name: "fallback to ping when openapi is unavailable",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/slurm/v0.0.40/ping/" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				io.WriteString(w, `{"pings": []}`)
+			},
+			wantVersion: "v0.0.40",
+		},
+		{
+			name: "no supported version",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(tc.handler)
+			defer server.Close()
+
+			client, err := NewClient(server.URL, AutoAPIVersion, "", "", logger)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			version, err := client.NegotiateVersion(context.Background())
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NegotiateVersion() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				if got := client.APIVersion(); got != AutoAPIVersion {
+					t.Errorf("APIVersion() after failed negotiation = %s, want %s", got, AutoAPIVersion)
+				}
+				return
+			}
+			if version != tc.wantVersion {
+				t.Errorf("NegotiateVersion() = %s, want %s", version, tc.wantVersion)
+			}
+			if got := client.APIVersion(); got != tc.wantVersion {
+				t.Errorf("APIVersion() = %s, want %s", got, tc.wantVersion)
+			}
+		})
+	}
+}
+
+func TestClient_GetNodes_NegotiatesVersion(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/openapi/v3":
+			io.WriteString(w, `{"paths": {"/slurm/v0.0.40/nodes/": {}}}`)
+		case "/slurm/v0.0.40/nodes/":
+			io.WriteString(w, `{"nodes": [{"name": "node1", "state": ["IDLE"], "partitions": ["compute"]}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, AutoAPIVersion, "", "", logger)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	resp, err := client.GetNodes(context.Background())
+	if err != nil {
+		t.Fatalf("GetNodes() error = %v", err)
+	}
+	if len(resp.Nodes) != 1 {
+		t.Errorf("GetNodes() got %d nodes, want 1", len(resp.Nodes))
+	}
+	if got := client.APIVersion(); got != "v0.0.40" {
+		t.Errorf("APIVersion() = %s, want v0.0.40", got)
+	}
+}
@@ -33,12 +33,16 @@ func main() {
 		String()
 	slurmApiEndpoint := app.Flag("slurm.api-endpoint", "Slurm REST API endpoint").
 		String()
-	slurmApiVersion := app.Flag("slurm.api-version", "Slurm REST API version").
+	slurmApiVersion := app.Flag("slurm.api-version", "Slurm REST API version, or \"auto\" to negotiate it").
 		String()
 	slurmApiUsername := app.Flag("slurm.api-username", "Slurm REST API username").
 		String()
 	slurmApiToken := app.Flag("slurm.api-token", "Slurm REST API token").
 		String()
+	slurmApiTokenFile := app.Flag("slurm.api-token-file", "File containing the Slurm REST API token, re-read when it changes").
+		String()
+	slurmJwtKeyFile := app.Flag("slurm.jwt-key-file", "Slurm jwt_hs256.key used to mint tokens locally").
+		String()
 	updateInterval := app.Flag("update.interval", "Update interval for fetching Slurm data").
 		String()
 
@@ -84,6 +88,12 @@ func main() {
 	if *slurmApiToken != "" {
 		cfg.SlurmAPIToken = *slurmApiToken
 	}
+	if *slurmApiTokenFile != "" {
+		cfg.SlurmAPITokenFile = *slurmApiTokenFile
+	}
+	if *slurmJwtKeyFile != "" {
+		cfg.SlurmJWTKeyFile = *slurmJwtKeyFile
+	}
 	if *updateInterval != "" {
 		cfg.UpdateInterval = *updateInterval
 	}
@@ -95,13 +105,62 @@ func main() {
 	}
 
 	// Create Slurm client
-	slurmClient := slurm.NewClient(
+	retryPolicy := slurm.RetryPolicy{MaxAttempts: cfg.SlurmAPIRetry.MaxAttempts}
+	if retryPolicy.BaseBackoff, err = time.ParseDuration(cfg.SlurmAPIRetry.BaseBackoff); err != nil {
+		logger.Error("Invalid Slurm API retry base backoff", "error", err)
+		os.Exit(1)
+	}
+	if retryPolicy.MaxBackoff, err = time.ParseDuration(cfg.SlurmAPIRetry.MaxBackoff); err != nil {
+		logger.Error("Invalid Slurm API retry max backoff", "error", err)
+		os.Exit(1)
+	}
+	clientOpts := []slurm.ClientOption{slurm.WithRetryPolicy(retryPolicy)}
+	switch {
+	case cfg.SlurmJWTKeyFile != "":
+		lifespan := slurm.DefaultJWTLifespan
+		if cfg.SlurmJWTLifespan != "" {
+			lifespan, err = time.ParseDuration(cfg.SlurmJWTLifespan)
+			if err != nil {
+				logger.Error("Invalid Slurm JWT lifespan", "error", err)
+				os.Exit(1)
+			}
+		}
+		clientOpts = append(clientOpts, slurm.WithJWTKeyFile(cfg.SlurmJWTKeyFile, lifespan))
+	case cfg.SlurmAPITokenFile != "":
+		clientOpts = append(clientOpts, slurm.WithTokenFile(cfg.SlurmAPITokenFile))
+	}
+	if tlsCfg := cfg.SlurmTLSConfig; tlsCfg != nil {
+		clientOpts = append(clientOpts, slurm.WithTLSConfig(slurm.TLSConfig{
+			CAFile:             tlsCfg.CAFile,
+			CertFile:           tlsCfg.CertFile,
+			KeyFile:            tlsCfg.KeyFile,
+			ServerName:         tlsCfg.ServerName,
+			InsecureSkipVerify: tlsCfg.InsecureSkipVerify,
+		}))
+	}
+
+	slurmClient, err := slurm.NewClient(
 		cfg.SlurmAPIEndpoint,
 		cfg.SlurmAPIVersion,
 		cfg.SlurmAPIUsername,
 		cfg.SlurmAPIToken,
 		logger,
+		clientOpts...,
 	)
+	if err != nil {
+		logger.Error("Failed to create Slurm client", "error", err)
+		os.Exit(1)
+	}
+
+	// Resolve the REST API version before the first refresh when negotiation is requested
+	if cfg.SlurmAPIVersion == slurm.AutoAPIVersion {
+		negotiateCtx, negotiateCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if _, err := slurmClient.NegotiateVersion(negotiateCtx); err != nil {
+			// Negotiation is retried on the next refresh
+			logger.Warn("Failed to negotiate Slurm REST API version", "error", err)
+		}
+		negotiateCancel()
+	}
 
 	// Create service discovery service
 	discoveryService, err := discovery.NewService(slurmClient, cfg, logger)
@@ -134,6 +193,8 @@ func main() {
 	// Set up HTTP server
 	mux := http.NewServeMux()
 	mux.HandleFunc("/targets", discoveryService.HTTPHandler())
+	mux.HandleFunc("/status", discoveryService.StatusHandler())
+	mux.HandleFunc("/metrics", discoveryService.MetricsHandler())
 
 	// Health check endpoint
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {